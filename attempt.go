@@ -0,0 +1,46 @@
+package waitforit
+
+import (
+	"context"
+	"time"
+)
+
+// Attempt describes a single attempt to reach a target
+type Attempt struct {
+	Name    string
+	Address string
+
+	// Index is the position of the config on its list and Group the name of
+	// the group it belongs to. Together they identify the target even when
+	// other configs have the same name or address.
+	Index int
+	Group string
+
+	Attempt int
+
+	// Err is nil when the target was in the expected state
+	Err error
+
+	// Ready reports if the attempt completed the wait for the target
+	Ready bool
+
+	Latency time.Duration
+	Elapsed time.Duration
+}
+
+type attemptHookKey struct{}
+
+// WithAttemptHook returns a copy of ctx which calls the hook after every
+// attempt made while dialing with it. The hook is called concurrently
+// when multiple targets are dialed at same time.
+func WithAttemptHook(ctx context.Context, hook func(Attempt)) context.Context {
+	return context.WithValue(ctx, attemptHookKey{}, hook)
+}
+
+// attemptHook returns the hook set on ctx or a no-op one
+func attemptHook(ctx context.Context) func(Attempt) {
+	if hook, ok := ctx.Value(attemptHookKey{}).(func(Attempt)); ok {
+		return hook
+	}
+	return func(Attempt) {}
+}
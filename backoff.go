@@ -0,0 +1,83 @@
+package waitforit
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Retry policies supported by Config.RetryPolicy
+const (
+	RetryFixed              = "fixed"
+	RetryExponential        = "exponential"
+	RetryDecorrelatedJitter = "decorrelated-jitter"
+)
+
+const (
+	defaultRetryMultiplier = 2
+	defaultRetryMax        = 30 * time.Second
+)
+
+// backoff computes the interval to wait between the attempts of a target
+type backoff struct {
+	policy     string
+	initial    time.Duration
+	max        time.Duration
+	multiplier float64
+	attempt    int
+	prev       time.Duration
+}
+
+func validateRetryPolicy(policy string) error {
+	switch policy {
+	case "", RetryFixed, RetryExponential, RetryDecorrelatedJitter:
+		return nil
+	}
+
+	return fmt.Errorf("Invalid retryPolicy %q: must be %s, %s or %s", policy, RetryFixed, RetryExponential, RetryDecorrelatedJitter)
+}
+
+func newBackoff(conf *Config) *backoff {
+	b := &backoff{
+		policy:     conf.RetryPolicy,
+		initial:    conf.retryInterval(),
+		max:        time.Duration(conf.RetryMax) * time.Millisecond,
+		multiplier: conf.RetryMultiplier,
+	}
+
+	if b.max <= 0 {
+		b.max = defaultRetryMax
+	}
+	if b.multiplier <= 1 {
+		b.multiplier = defaultRetryMultiplier
+	}
+	b.prev = b.initial
+
+	return b
+}
+
+// next returns how long to wait before the next attempt
+func (b *backoff) next() time.Duration {
+	defer func() { b.attempt++ }()
+
+	if b.initial <= 0 {
+		return 0
+	}
+
+	switch b.policy {
+	case RetryExponential:
+		// equal jitter: half of the exponential interval plus a random part of the other half
+		d := float64(b.initial) * math.Pow(b.multiplier, float64(b.attempt))
+		d = math.Min(d, float64(b.max))
+		return time.Duration(d/2 + rand.Float64()*d/2)
+	case RetryDecorrelatedJitter:
+		// random between the initial interval and the previous one times the multiplier
+		upper := math.Min(float64(b.prev)*b.multiplier, float64(b.max))
+		d := float64(b.initial) + rand.Float64()*math.Max(upper-float64(b.initial), 0)
+		b.prev = time.Duration(d)
+		return b.prev
+	}
+
+	return b.initial
+}
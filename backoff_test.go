@@ -0,0 +1,82 @@
+package waitforit_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	. "github.com/maxcnunes/waitforit"
+)
+
+// retryIntervals records the intervals between the attempts of a target that never succeeds
+func retryIntervals(t *testing.T, cfg Config, attempts int) []time.Duration {
+	var times []time.Time
+	scheme := "backoff-" + cfg.RetryPolicy
+	RegisterProber(scheme, ProberFunc(func(ctx context.Context, conn *Connection) error {
+		times = append(times, time.Now())
+		if len(times) > attempts {
+			return nil
+		}
+		return errors.New("not ready")
+	}))
+
+	cfg.Address = scheme + "://localhost:1234"
+	cfg.Timeout = 30
+	conn, err := BuildConn(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DialConn(context.Background(), conn, slog.New(slog.DiscardHandler)); err != nil {
+		t.Fatal(err)
+	}
+
+	intervals := make([]time.Duration, len(times)-1)
+	for i := range intervals {
+		intervals[i] = times[i+1].Sub(times[i])
+	}
+	return intervals
+}
+
+func TestRetryPolicies(t *testing.T) {
+	const tolerance = 30 * time.Millisecond
+
+	t.Run("Should wait the same interval on fixed policy", func(t *testing.T) {
+		for _, d := range retryIntervals(t, Config{RetryPolicy: RetryFixed, Retry: 50}, 4) {
+			if d < 50*time.Millisecond || d > 50*time.Millisecond+tolerance {
+				t.Errorf("Expected fixed interval of 50ms, got %v", d)
+			}
+		}
+	})
+
+	t.Run("Should grow the interval up to the max on exponential policy", func(t *testing.T) {
+		cfg := Config{RetryPolicy: RetryExponential, Retry: 40, RetryMax: 320, RetryMultiplier: 2}
+		for i, d := range retryIntervals(t, cfg, 6) {
+			full := 40 * time.Millisecond << uint(i)
+			if full > 320*time.Millisecond {
+				full = 320 * time.Millisecond
+			}
+
+			if d < full/2 || d > full+tolerance {
+				t.Errorf("Expected attempt %d interval between %v and %v, got %v", i, full/2, full, d)
+			}
+		}
+	})
+
+	t.Run("Should keep the interval between the initial and max on decorrelated jitter policy", func(t *testing.T) {
+		cfg := Config{RetryPolicy: RetryDecorrelatedJitter, Retry: 20, RetryMax: 200, RetryMultiplier: 3}
+		for _, d := range retryIntervals(t, cfg, 8) {
+			if d < 20*time.Millisecond || d > 200*time.Millisecond+tolerance {
+				t.Errorf("Expected interval between 20ms and 200ms, got %v", d)
+			}
+		}
+	})
+}
+
+func TestRetryPolicyValidation(t *testing.T) {
+	if _, err := BuildConn(&Config{Address: "localhost:80", RetryPolicy: "linear"}); err == nil {
+		t.Error("Expected unknown retry policy to be invalid")
+	}
+}
@@ -0,0 +1,141 @@
+package waitforit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxBodySize limits how much of the response body is read for the assertions
+const maxBodySize = 1 << 20
+
+// hasBodyAssertions returns true when the response body has to be checked
+func (c *Config) hasBodyAssertions() bool {
+	return c.BodyContains != "" || c.BodyMatches != "" || c.JSONPath != ""
+}
+
+// checkBody asserts the response body matches the config expectations
+func checkBody(conf *Config, body []byte) error {
+	if conf.BodyContains != "" && !strings.Contains(string(body), conf.BodyContains) {
+		return fmt.Errorf("Response body does not contain %q", conf.BodyContains)
+	}
+
+	if conf.BodyMatches != "" {
+		re, err := regexp.Compile(conf.BodyMatches)
+		if err != nil {
+			return err
+		}
+
+		if !re.Match(body) {
+			return fmt.Errorf("Response body does not match %q", conf.BodyMatches)
+		}
+	}
+
+	if conf.JSONPath != "" {
+		expr, err := parseJSONPathExpr(conf.JSONPath)
+		if err != nil {
+			return err
+		}
+
+		return expr.check(body)
+	}
+
+	return nil
+}
+
+// jsonPathExpr is an assertion like `$.status == "UP"`.
+// Without the comparison it only asserts the path exists.
+type jsonPathExpr struct {
+	raw      string
+	path     []interface{} // string keys and int indexes
+	expected interface{}
+	compare  bool
+}
+
+func parseJSONPathExpr(s string) (*jsonPathExpr, error) {
+	expr := &jsonPathExpr{raw: s}
+
+	path := s
+	if i := strings.Index(s, "=="); i >= 0 {
+		path = s[:i]
+		expr.compare = true
+		if err := json.Unmarshal([]byte(strings.TrimSpace(s[i+2:])), &expr.expected); err != nil {
+			return nil, fmt.Errorf("Invalid JSON path expected value in %q: %v", s, err)
+		}
+	}
+
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("Invalid JSON path %q: must start with $", path)
+	}
+
+	for rest := path[1:]; rest != ""; {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("Invalid JSON path %q: empty key", path)
+			}
+			expr.path = append(expr.path, key)
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("Invalid JSON path %q: missing ]", path)
+			}
+			sel := rest[1:end]
+			if key, err := strconv.Unquote(strings.Replace(sel, "'", `"`, -1)); err == nil {
+				expr.path = append(expr.path, key)
+			} else if idx, err := strconv.Atoi(sel); err == nil {
+				expr.path = append(expr.path, idx)
+			} else {
+				return nil, fmt.Errorf("Invalid JSON path %q: bad selector [%s]", path, sel)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("Invalid JSON path %q", path)
+		}
+	}
+
+	return expr, nil
+}
+
+func (e *jsonPathExpr) check(body []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Errorf("Response body is not valid JSON: %v", err)
+	}
+
+	for _, p := range e.path {
+		switch key := p.(type) {
+		case string:
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("JSON path %q not found in response body", e.raw)
+			}
+			if v, ok = obj[key]; !ok {
+				return fmt.Errorf("JSON path %q not found in response body", e.raw)
+			}
+		case int:
+			arr, ok := v.([]interface{})
+			if !ok || key < 0 || key >= len(arr) {
+				return fmt.Errorf("JSON path %q not found in response body", e.raw)
+			}
+			v = arr[key]
+		}
+	}
+
+	if e.compare && !reflect.DeepEqual(v, e.expected) {
+		got, _ := json.Marshal(v)
+		return fmt.Errorf("JSON path %q failed, got %s", e.raw, got)
+	}
+
+	return nil
+}
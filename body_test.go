@@ -0,0 +1,123 @@
+package waitforit_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/maxcnunes/waitforit"
+)
+
+func TestBodyAssertions(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"UP","cluster":{"status":"yellow","nodes":[{"name":"es-1"}]},"shards":3}`)) // nolint
+	}))
+	defer s.Close()
+
+	testCases := []struct {
+		title    string
+		cfg      Config
+		finishOk bool
+	}{
+		{
+			title:    "Should succeed when the body contains the text",
+			cfg:      Config{BodyContains: `"status":"UP"`},
+			finishOk: true,
+		},
+		{
+			title:    "Should fail when the body does not contain the text",
+			cfg:      Config{BodyContains: `"status":"DOWN"`},
+			finishOk: false,
+		},
+		{
+			title:    "Should succeed when the body matches the regular expression",
+			cfg:      Config{BodyMatches: `"status":"(green|yellow)"`},
+			finishOk: true,
+		},
+		{
+			title:    "Should fail when the body does not match the regular expression",
+			cfg:      Config{BodyMatches: `"status":"green"`},
+			finishOk: false,
+		},
+		{
+			title:    "Should succeed when the JSON path equals the expected value",
+			cfg:      Config{JSONPath: `$.status == "UP"`},
+			finishOk: true,
+		},
+		{
+			title:    "Should succeed when a nested JSON path equals the expected value",
+			cfg:      Config{JSONPath: `$.cluster.nodes[0]['name'] == "es-1"`},
+			finishOk: true,
+		},
+		{
+			title:    "Should succeed when a JSON path equals a number",
+			cfg:      Config{JSONPath: `$.shards == 3`},
+			finishOk: true,
+		},
+		{
+			title:    "Should succeed when the JSON path exists",
+			cfg:      Config{JSONPath: `$.cluster.status`},
+			finishOk: true,
+		},
+		{
+			title:    "Should fail when the JSON path has a different value",
+			cfg:      Config{JSONPath: `$.cluster.status == "green"`},
+			finishOk: false,
+		},
+		{
+			title:    "Should fail when the JSON path does not exist",
+			cfg:      Config{JSONPath: `$.cluster.nodes[1]`},
+			finishOk: false,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			cfg := v.cfg
+			cfg.Address = s.URL
+			cfg.Timeout = 1
+			cfg.Retry = 200
+
+			conn, err := BuildConn(&cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = DialConn(context.Background(), conn, logger)
+			if err != nil && v.finishOk {
+				t.Errorf("Expected to connect successfully %s. But got error %v.", cfg.Address, err)
+			}
+
+			if err == nil && !v.finishOk {
+				t.Errorf("Expected to not connect successfully %s.", cfg.Address)
+			}
+		})
+	}
+}
+
+func TestBodyAssertionsValidation(t *testing.T) {
+	testCases := []struct {
+		title string
+		cfg   Config
+	}{
+		{"Should fail with an invalid regular expression", Config{BodyMatches: "("}},
+		{"Should fail with a JSON path not starting with $", Config{JSONPath: "status == 1"}},
+		{"Should fail with an invalid JSON path expected value", Config{JSONPath: "$.status == UP"}},
+		{"Should fail with an unclosed JSON path selector", Config{JSONPath: "$.nodes[0"}},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			cfg := v.cfg
+			cfg.Address = "http://localhost"
+			if _, err := BuildConn(&cfg); err == nil {
+				t.Errorf("Expected config %#v to be invalid", cfg)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+// postCommand returns the command given after the explicit "--" argument
+func postCommand() []string {
+	extraArgs := flag.Args()
+	nExtraArgs := len(extraArgs)
+	if nExtraArgs == 0 {
+		return nil
+	}
+
+	// Ensure with explict argument "--" is enabling a post command
+	allArgs := os.Args
+	nAllArgs := len(allArgs)
+	if allArgs[nAllArgs-(nExtraArgs+1)] != "--" {
+		return nil
+	}
+
+	return extraArgs
+}
+
+// startCommand starts the command as a child process
+// returning a channel closed once it exits
+func startCommand(args []string) (*exec.Cmd, <-chan struct{}, error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = os.Stdout
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait() // nolint
+		close(exited)
+	}()
+
+	return cmd, exited, nil
+}
+
+// startFailureCode returns the exit code used by shells
+// when the command could not be started
+func startFailureCode(err error) int {
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+		return 127
+	}
+	return 126
+}
+
+// runCommand runs the command as a child process forwarding the signals
+// received by waitforit and returns the exit code of the child
+func runCommand(args []string) int {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, forwardedSignals...)
+	defer signal.Stop(sigs)
+
+	cmd, exited, err := startCommand(args)
+	if err != nil {
+		log.Print(err)
+		return startFailureCode(err)
+	}
+
+	for {
+		select {
+		case sig := <-sigs:
+			forwardSignal(cmd, sig)
+		case <-exited:
+			return exitCode(cmd.ProcessState)
+		}
+	}
+}
+
+// forwardSignal sends the signal received by waitforit to the command,
+// unless it was sent to the whole process group and so the command got it too
+func forwardSignal(cmd *exec.Cmd, sig os.Signal) {
+	if sentToProcessGroup(sig) {
+		return
+	}
+
+	cmd.Process.Signal(sig) // nolint
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// forwardedSignals are the signals sent to the post-command when received by waitforit
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// sentToProcessGroup reports if the signal already reached the post-command,
+// as the console sends Ctrl-C to every process attached to it
+func sentToProcessGroup(sig os.Signal) bool {
+	return sig == os.Interrupt
+}
+
+// parseSignal returns the signal by its name. Only INT and KILL are supported on windows.
+func parseSignal(name string) (os.Signal, error) {
+	switch strings.TrimPrefix(strings.ToUpper(name), "SIG") {
+	case "INT":
+		return os.Interrupt, nil
+	case "KILL":
+		return os.Kill, nil
+	}
+	return nil, fmt.Errorf("Invalid signal %q: only INT and KILL are supported on windows", name)
+}
+
+// signalExitCode returns the exit code used when terminated by the signal
+func signalExitCode(sig os.Signal) int {
+	return 1
+}
+
+// execCommand is not supported on windows since it cannot replace the current process
+func execCommand(args []string) error {
+	return errors.New("Replacing the process with the post-command is not supported on windows")
+}
+
+// exitCode returns the exit code of the process
+func exitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	testCases := []struct {
+		title  string
+		level  string
+		format string
+		output string
+		err    string
+	}{
+		{
+			title:  "text records from the level",
+			level:  "info",
+			format: logFormatText,
+			output: "level=INFO msg=Up target=api",
+		},
+		{
+			title:  "json records from the level",
+			level:  "INFO",
+			format: logFormatJSON,
+			output: `"level":"INFO","msg":"Up","target":"api"`,
+		},
+		{
+			title:  "records below the level are discarded",
+			level:  "warn",
+			format: logFormatText,
+		},
+		{
+			title:  "invalid level",
+			level:  "verbose",
+			format: logFormatText,
+			err:    `Invalid log level "verbose": must be debug, info, warn or error`,
+		},
+		{
+			title:  "invalid format",
+			level:  "info",
+			format: "xml",
+			err:    `Invalid log format "xml": must be text or json`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			var b bytes.Buffer
+			logger, err := newLogger(&b, tc.level, tc.format)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf(`Expected to fail with "%v", got "%v"`, tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			logger.Info("Up", "target", "api")
+			if tc.output == "" && b.Len() > 0 {
+				t.Errorf("Expected no output, got %q", b.String())
+			}
+			if !strings.Contains(b.String(), tc.output) {
+				t.Errorf("Expected %q in the output, got %q", tc.output, b.String())
+			}
+		})
+	}
+}
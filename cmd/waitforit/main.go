@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/maxcnunes/waitforit"
+)
+
+// VERSION is definded during the build
+var VERSION string
+
+type arrayFlags []string
+
+func (i *arrayFlags) String() string {
+	return ""
+}
+
+func (i *arrayFlags) Set(value string) error {
+	*i = append(*i, value)
+	return nil
+}
+
+func main() { // nolint gocyclo
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n\n  %s [options] [-- post-command]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "The options are:\n\n")
+		flag.PrintDefaults()
+	}
+
+	var fheaders arrayFlags
+
+	address := flag.String("address", "", "address (e.g. http://google.com or tcp://mysql_ip:mysql_port)")
+	proto := flag.String("proto", "", "protocol to use during the connection")
+	host := flag.String("host", "", "host to connect")
+	port := flag.Int("port", 0, "port to connect")
+	status := flag.String("status", "", "expected status that address should return (e.g. 200 or 200-299,301,401)")
+	timeout := flag.Int("timeout", 10, "seconds to wait until the address become available")
+	retry := flag.Int("retry", 500, "milliseconds to wait between retries")
+	attemptTimeout := flag.Int("attempt-timeout", 5000, "milliseconds to wait for a single attempt (dial, tls handshake and request)")
+	until := flag.String("until", "up", "wait until the address is up or down")
+	successThreshold := flag.Int("success-threshold", 1, "consecutive successes required before the address is considered available")
+	stableFor := flag.Int("stable-for", 0, "milliseconds the address must keep responding before it is considered available")
+	retryPolicy := flag.String("retry-policy", "fixed", "retry policy: fixed, exponential or decorrelated-jitter")
+	retryMax := flag.Int("retry-max", 30000, "maximum milliseconds to wait between retries on exponential policies")
+	retryMultiplier := flag.Float64("retry-multiplier", 2, "multiplier applied to the interval between retries on exponential policies")
+	insecure := flag.Bool("insecure", false, "allows waitforit to perform \"insecure\" SSL connections")
+	caFile := flag.String("ca-file", "", "path of a PEM bundle with the CAs used to verify the server certificate")
+	certFile := flag.String("cert-file", "", "path of the PEM client certificate used on mutual TLS")
+	keyFile := flag.String("key-file", "", "path of the PEM client key used on mutual TLS")
+	serverName := flag.String("server-name", "", "server name used on TLS verification and SNI")
+	certHostname := flag.String("cert-hostname", "", "hostname the tls certificate must be valid for")
+	certMinDays := flag.Int("cert-min-days", 0, "minimum number of days before the tls certificate expires")
+	certIssuer := flag.String("cert-issuer", "", "expected issuer (common name, organization or DN) of the tls certificate")
+	printVersion := flag.Bool("v", false, "show the current version")
+	debug := flag.Bool("debug", false, "enable debug (same as -log-level=debug)")
+	logLevel := flag.String("log-level", "warn", "minimum level of the log records: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "format of the log records: text or json")
+	file := flag.String("file", "", "path of json file to read configs from")
+	execPostCommand := flag.Bool("exec", false, "replace the waitforit process with the post-command instead of running it as a child")
+	output := flag.String("output", "text", "format of the result: text or json")
+	events := flag.String("events", "", "path of a file to stream every attempt as NDJSON events (- for stdout)")
+	serveAddr := flag.String("serve", "", "keep monitoring the configs and serve /ready, /live and /status on the address (e.g. :8080)")
+	supervise := flag.String("supervise", "", "keep probing after the post-command starts and, when a dependency is lost, signal, restart or exit")
+	superviseSig := flag.String("supervise-signal", "TERM", "signal sent to the post-command when a supervised dependency is lost")
+	superviseFailures := flag.Int("supervise-failures", 3, "consecutive failed probes before a supervised dependency is considered lost")
+	superviseGrace := flag.Int("supervise-grace", 10000, "milliseconds to wait for the post-command to stop after the signal before killing it")
+	flag.Var(&fheaders, "header", "list of headers sent in the http(s) ping request")
+	payload := flag.String("payload", "", "text payload sent in the udp ping request")
+	payloadHex := flag.String("payload-hex", "", "hex encoded payload sent in the udp ping request")
+	responsePattern := flag.String("response-pattern", "", "regular expression the udp response should match")
+	query := flag.String("query", "", "query to run once connected to a database (e.g. SELECT 1)")
+	method := flag.String("method", "GET", "http method used in the http(s) ping request")
+	body := flag.String("body", "", "body sent in the http(s) ping request")
+	bodyFile := flag.String("body-file", "", "path of a file with the body sent in the http(s) ping request")
+	contentType := flag.String("content-type", "", "content type of the body sent in the http(s) ping request")
+	bodyContains := flag.String("body-contains", "", "text the http(s) response body should contain")
+	bodyMatches := flag.String("body-matches", "", "regular expression the http(s) response body should match")
+	jsonPath := flag.String("json-path", "", "JSON path assertion on the http(s) response body (e.g. '$.status == \"UP\"')")
+
+	flag.Parse()
+
+	if *printVersion {
+		fmt.Println("waitforit version " + VERSION)
+		return
+	}
+
+	if *address == "" && *host == "" && *file == "" {
+		fmt.Fprintf(os.Stderr, "Missing : address, host or file field\n")
+		flag.Usage()
+		return
+	}
+
+	if *debug {
+		*logLevel = "debug"
+	}
+
+	logger, err := newLogger(os.Stderr, *logLevel, *logFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := validateOutput(*output, *events); err != nil {
+		log.Fatal(err)
+	}
+
+	var sv *supervisor
+	if *supervise != "" {
+		if err := validateSuperviseAction(*supervise); err != nil {
+			log.Fatal(err)
+		}
+
+		sig, err := parseSignal(*superviseSig)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if *superviseFailures < 1 {
+			log.Fatal("Invalid supervise failures: must be at least 1")
+		}
+
+		if *superviseGrace < 0 {
+			log.Fatal("Invalid supervise grace: must not be negative")
+		}
+
+		if *execPostCommand || len(postCommand()) == 0 {
+			log.Fatal("Supervise requires a post command running as a child (without -exec)")
+		}
+
+		sv = &supervisor{
+			action:   *supervise,
+			signal:   sig,
+			failures: *superviseFailures,
+			grace:    time.Duration(*superviseGrace) * time.Millisecond,
+			logger:   logger,
+		}
+	}
+
+	var fc waitforit.FileConfig
+	if *file != "" {
+		if err := loadFileConfig(*file, &fc); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		headers := make(map[string]string)
+		if len(fheaders) > 0 {
+			for _, v := range fheaders {
+				result := strings.SplitN(v, ":", 2)
+				if len(result) != 2 {
+					continue
+				}
+				headers[result[0]] = strings.TrimLeft(result[1], " ")
+			}
+		}
+
+		fc = waitforit.FileConfig{
+			Configs: []waitforit.Config{
+				{
+					Protocol: *proto,
+					Host:     *host,
+					Port:     *port,
+					Address:  *address,
+					Status:   waitforit.StatusCodes(*status),
+					Timeout:  *timeout,
+					Insecure: *insecure,
+					Retry:    *retry,
+					Headers:  headers,
+
+					RetryPolicy:     *retryPolicy,
+					RetryMax:        *retryMax,
+					RetryMultiplier: *retryMultiplier,
+					AttemptTimeout:  *attemptTimeout,
+
+					SuccessThreshold: *successThreshold,
+					StableFor:        *stableFor,
+
+					Expect: *until,
+
+					CAFile:     *caFile,
+					CertFile:   *certFile,
+					KeyFile:    *keyFile,
+					ServerName: *serverName,
+
+					CertHostname: *certHostname,
+					CertMinDays:  *certMinDays,
+					CertIssuer:   *certIssuer,
+
+					Payload:         *payload,
+					PayloadHex:      *payloadHex,
+					ResponsePattern: *responsePattern,
+
+					Query: *query,
+
+					BodyContains: *bodyContains,
+					BodyMatches:  *bodyMatches,
+					JSONPath:     *jsonPath,
+
+					Method:      *method,
+					Body:        *body,
+					BodyFile:    *bodyFile,
+					ContentType: *contentType,
+				},
+			},
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if *serveAddr != "" {
+		if len(postCommand()) > 0 {
+			log.Fatal("Serve cannot be used with a post command")
+		}
+
+		err := serve(ctx, *serveAddr, fc, logger)
+		stop()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	var eventsOut io.Writer
+	if *events == "-" {
+		eventsOut = os.Stdout
+	} else if *events != "" {
+		f, err := os.Create(*events)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// events are written unbuffered, so the file is left to be closed on exit
+		eventsOut = f
+	}
+
+	rep := newReport(fc, eventsOut)
+	err = waitforit.DialFileConfig(waitforit.WithAttemptHook(ctx, rep.hook), fc, logger)
+	stop()
+
+	if *output == outputJSON {
+		if err := rep.write(os.Stdout, err); err != nil {
+			log.Print(err)
+		}
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	args := postCommand()
+	if len(args) == 0 {
+		return
+	}
+
+	if *execPostCommand {
+		log.Fatal(execCommand(args))
+	}
+
+	if sv != nil {
+		if sv.monitor, err = waitforit.NewMonitor(fc, logger); err != nil {
+			log.Fatal(err)
+		}
+		sv.args = args
+		os.Exit(sv.run())
+	}
+
+	os.Exit(runCommand(args))
+}
+
+func loadFileConfig(path string, fc *waitforit.FileConfig) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewDecoder(f).Decode(&fc); err != nil {
+		return err
+	}
+
+	return nil
+}
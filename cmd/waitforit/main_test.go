@@ -2,7 +2,10 @@ package main
 
 import (
 	"fmt"
+	"runtime"
 	"testing"
+
+	"github.com/maxcnunes/waitforit"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -29,7 +32,7 @@ func TestLoadConfig(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.title, func(t *testing.T) {
-			var fc FileConfig
+			var fc waitforit.FileConfig
 			err := loadFileConfig(tc.file, &fc)
 			errMsg := fmt.Sprintf("%v", err)
 
@@ -41,3 +44,44 @@ func TestLoadConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestRunCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a unix shell")
+	}
+
+	testCases := []struct {
+		title string
+		args  []string
+		code  int
+	}{
+		{
+			title: "successful command",
+			args:  []string{"sh", "-c", "exit 0"},
+			code:  0,
+		},
+		{
+			title: "failed command",
+			args:  []string{"sh", "-c", "exit 3"},
+			code:  3,
+		},
+		{
+			title: "command killed by a signal",
+			args:  []string{"sh", "-c", "kill -TERM $$"},
+			code:  143,
+		},
+		{
+			title: "not existing command",
+			args:  []string{"./testdata/nothing-here"},
+			code:  127,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			if code := runCommand(tc.args); code != tc.code {
+				t.Errorf("Expected exit code %d, got %d", tc.code, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/maxcnunes/waitforit"
+)
+
+// Formats supported by -output
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+func validateOutput(output, events string) error {
+	if output != outputText && output != outputJSON {
+		return fmt.Errorf("Invalid output %q: must be %s or %s", output, outputText, outputJSON)
+	}
+
+	// the events would be mixed with the json document
+	if output == outputJSON && events == "-" {
+		return errors.New("Invalid events \"-\": stdout is already used by the json output")
+	}
+
+	return nil
+}
+
+// report collects the result of every target for the json output
+// and optionally streams every attempt as NDJSON events
+type report struct {
+	mu      sync.Mutex
+	start   time.Time
+	events  *json.Encoder
+	targets []*targetReport
+	byKey   map[targetKey]*targetReport
+}
+
+// targetKey identifies a target by the group and position of its config,
+// as names and addresses may be repeated
+type targetKey struct {
+	group string
+	index int
+}
+
+type targetReport struct {
+	Name          string   `json:"name,omitempty"`
+	Group         string   `json:"group,omitempty"`
+	Address       string   `json:"address"`
+	Success       bool     `json:"success"`
+	Attempts      int      `json:"attempts"`
+	TimeToReadyMs *float64 `json:"timeToReadyMs,omitempty"`
+	ElapsedMs     float64  `json:"elapsedMs"`
+	LastError     string   `json:"lastError,omitempty"`
+	BlockedBy     string   `json:"blockedBy,omitempty"`
+}
+
+type attemptEvent struct {
+	Time      time.Time `json:"time"`
+	Name      string    `json:"name,omitempty"`
+	Group     string    `json:"group,omitempty"`
+	Address   string    `json:"address"`
+	Attempt   int       `json:"attempt"`
+	Success   bool      `json:"success"`
+	Ready     bool      `json:"ready"`
+	LatencyMs float64   `json:"latencyMs"`
+	ElapsedMs float64   `json:"elapsedMs"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// newReport creates an entry for every config of the file
+// with the address resolved by waitforit.BuildConn
+func newReport(fc waitforit.FileConfig, events io.Writer) *report {
+	r := &report{start: time.Now(), byKey: make(map[targetKey]*targetReport)}
+	if events != nil {
+		r.events = json.NewEncoder(events)
+	}
+
+	r.addTargets(fc.Configs, "")
+	for _, g := range fc.Groups {
+		r.addTargets(g.Configs, g.Name)
+	}
+
+	return r
+}
+
+func (r *report) addTargets(confs []waitforit.Config, group string) {
+	for i := range confs {
+		conf := confs[i]
+		t := &targetReport{Name: conf.Name, Group: group, Address: waitforit.RedactAddress(conf.Address)}
+		if conn, err := waitforit.BuildConn(&conf); err == nil {
+			t.Address = conn.URL.Redacted()
+		}
+
+		r.targets = append(r.targets, t)
+		r.byKey[targetKey{group: group, index: i}] = t
+	}
+}
+
+// hook records the attempt, it is used with waitforit.WithAttemptHook
+func (r *report) hook(a waitforit.Attempt) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t, ok := r.byKey[targetKey{group: a.Group, index: a.Index}]; ok {
+		t.Attempts = a.Attempt
+		t.ElapsedMs = milliseconds(a.Elapsed)
+		t.LastError = ""
+		if a.Err != nil {
+			t.LastError = a.Err.Error()
+		}
+		if a.Ready {
+			t.Success = true
+			ready := milliseconds(a.Elapsed)
+			t.TimeToReadyMs = &ready
+		}
+	}
+
+	if r.events == nil {
+		return
+	}
+
+	ev := attemptEvent{
+		Time:      time.Now(),
+		Name:      a.Name,
+		Group:     a.Group,
+		Address:   a.Address,
+		Attempt:   a.Attempt,
+		Success:   a.Err == nil,
+		Ready:     a.Ready,
+		LatencyMs: milliseconds(a.Latency),
+		ElapsedMs: milliseconds(a.Elapsed),
+	}
+	if a.Err != nil {
+		ev.Error = a.Err.Error()
+	}
+	r.events.Encode(ev) // nolint
+}
+
+// write renders the final document with the result of the wait
+func (r *report) write(w io.Writer, err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var targetErrs []*waitforit.TargetError
+	var de *waitforit.DialError
+	var ge *waitforit.GroupError
+	if errors.As(err, &de) {
+		targetErrs = append(targetErrs, de.Targets...)
+		for _, g := range de.Groups {
+			targetErrs = append(targetErrs, g.Targets...)
+		}
+	} else if errors.As(err, &ge) {
+		targetErrs = ge.Targets
+	}
+
+	for _, te := range targetErrs {
+		if t, ok := r.byKey[targetKey{group: te.Group, index: te.Index}]; ok {
+			t.Address = te.Address
+			t.LastError = te.Err.Error()
+			t.BlockedBy = te.BlockedBy
+			if te.Attempts > 0 {
+				t.Attempts = te.Attempts
+				t.ElapsedMs = milliseconds(te.Elapsed)
+			}
+		}
+	}
+
+	doc := struct {
+		Success   bool            `json:"success"`
+		ElapsedMs float64         `json:"elapsedMs"`
+		Error     string          `json:"error,omitempty"`
+		Targets   []*targetReport `json:"targets"`
+	}{
+		Success:   err == nil,
+		ElapsedMs: milliseconds(time.Since(r.start)),
+		Targets:   r.targets,
+	}
+	if err != nil {
+		doc.Error = err.Error()
+	}
+
+	return json.NewEncoder(w).Encode(doc)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/maxcnunes/waitforit"
+)
+
+func TestReport(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close() // nolint
+
+	up := "tcp://" + l.Addr().String()
+	fc := waitforit.FileConfig{
+		Configs: []waitforit.Config{
+			{Name: "db", Host: "127.0.0.1", Port: l.Addr().(*net.TCPAddr).Port, Timeout: 1, Retry: 200},
+			{Name: "cache", Address: "localhost:8087", Timeout: 1, Retry: 200},
+			{Name: "api", DependsOn: []string{"cache"}, Address: "localhost:8088", Timeout: 1, Retry: 200},
+			{Address: "localhost:8087", Timeout: 1, Retry: 200},
+			{Address: "localhost:8087", Timeout: 1, Retry: 400},
+		},
+		Groups: []waitforit.Group{
+			{Name: "replicas", Mode: waitforit.GroupAll, Configs: []waitforit.Config{
+				{Address: up, Timeout: 1},
+			}},
+		},
+	}
+
+	var events, out bytes.Buffer
+	rep := newReport(fc, &events)
+	err = waitforit.DialFileConfig(waitforit.WithAttemptHook(context.Background(), rep.hook), fc, logger)
+	if err == nil {
+		t.Fatal("Expected to not connect successfully")
+	}
+
+	if err := rep.write(&out, err); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Success bool
+		Targets []targetReport
+	}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Success {
+		t.Error("Expected the report to fail")
+	}
+
+	if len(doc.Targets) != 6 {
+		t.Fatalf("Expected 6 targets, got %d", len(doc.Targets))
+	}
+
+	db, cache, api := doc.Targets[0], doc.Targets[1], doc.Targets[2]
+	if db.Address != up || !db.Success || db.Attempts != 1 || db.TimeToReadyMs == nil {
+		t.Errorf("Unexpected db report %+v", db)
+	}
+	if cache.Success || cache.Attempts < 2 || cache.LastError == "" {
+		t.Errorf("Unexpected cache report %+v", cache)
+	}
+	if api.Success || api.Attempts != 0 || api.BlockedBy != "cache" {
+		t.Errorf("Unexpected api report %+v", api)
+	}
+
+	// unnamed configs on the same address are reported apart
+	first, second := doc.Targets[3], doc.Targets[4]
+	if first.Success || second.Success || first.Attempts <= second.Attempts || second.Attempts < 2 {
+		t.Errorf("Unexpected unnamed reports %+v and %+v", first, second)
+	}
+
+	replica := doc.Targets[5]
+	if replica.Group != "replicas" || replica.Address != up || !replica.Success || replica.Attempts != 1 {
+		t.Errorf("Unexpected replica report %+v", replica)
+	}
+
+	lines := strings.Split(strings.TrimSpace(events.String()), "\n")
+	total := 0
+	for _, tr := range doc.Targets {
+		total += tr.Attempts
+	}
+	if len(lines) != total {
+		t.Errorf("Expected an event per attempt, got %d", len(lines))
+	}
+
+	grouped := 0
+	for _, line := range lines {
+		var ev attemptEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Errorf("Invalid event %q: %v", line, err)
+		}
+		if ev.Group == "replicas" {
+			grouped++
+		}
+	}
+	if grouped != replica.Attempts {
+		t.Errorf("Expected %d events of the group, got %d", replica.Attempts, grouped)
+	}
+}
+
+func TestReportInvalidAddressRedacted(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	fc := waitforit.FileConfig{
+		Configs: []waitforit.Config{
+			{Address: "postgres://user:secret@db/app", Status: "999"},
+		},
+		Groups: []waitforit.Group{
+			{Name: "replicas", Configs: []waitforit.Config{
+				{Address: "mysql://root:hunter2@db/app", Status: "999"},
+			}},
+		},
+	}
+
+	var out bytes.Buffer
+	rep := newReport(fc, nil)
+	err := waitforit.DialFileConfig(context.Background(), fc, logger)
+	if err == nil {
+		t.Fatal("Expected invalid configs to fail")
+	}
+
+	if err := rep.write(&out, err); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, password := range []string{"secret", "hunter2"} {
+		if strings.Contains(out.String(), password) {
+			t.Errorf("Expected the password %q to be redacted, got %s", password, out.String())
+		}
+	}
+}
+
+func TestValidateOutput(t *testing.T) {
+	testCases := []struct {
+		output string
+		events string
+		err    string
+	}{
+		{output: outputText, events: "-"},
+		{output: outputJSON, events: "attempts.ndjson"},
+		{output: "xml", err: `Invalid output "xml": must be text or json`},
+		{output: outputJSON, events: "-", err: `Invalid events "-": stdout is already used by the json output`},
+	}
+
+	for _, tc := range testCases {
+		err := validateOutput(tc.output, tc.events)
+		if tc.err == "" && err != nil {
+			t.Errorf("Expected output %q with events %q to be valid, got %v", tc.output, tc.events, err)
+		}
+		if tc.err != "" && (err == nil || err.Error() != tc.err) {
+			t.Errorf("Expected error %q, got %v", tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/maxcnunes/waitforit"
+)
+
+// shutdownTimeout limits how long the sidecar server waits for pending requests
+const shutdownTimeout = 5 * time.Second
+
+// serve keeps monitoring the configs and serves their status
+// on the address until the context is done
+func serve(ctx context.Context, addr string, fc waitforit.FileConfig, logger *slog.Logger) error {
+	m, err := waitforit.NewMonitor(fc, logger)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{Addr: addr, Handler: waitforit.StatusHandler(m)}
+
+	go m.Run(ctx)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx) // nolint
+	}()
+
+	logger.Info("Serving status", "address", addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"time"
+
+	"github.com/maxcnunes/waitforit"
+)
+
+// Actions supported by -supervise when a dependency is lost
+const (
+	superviseSignal  = "signal"
+	superviseRestart = "restart"
+	superviseExit    = "exit"
+)
+
+// exitDependencyLost is the exit code used when a supervised
+// dependency is lost (EX_UNAVAILABLE from sysexits.h)
+const exitDependencyLost = 69
+
+func validateSuperviseAction(action string) error {
+	switch action {
+	case superviseSignal, superviseRestart, superviseExit:
+		return nil
+	}
+
+	return fmt.Errorf("Invalid supervise action %q: must be %s, %s or %s", action, superviseSignal, superviseRestart, superviseExit)
+}
+
+// supervisor runs the post-command while monitoring its dependencies
+type supervisor struct {
+	args     []string
+	monitor  *waitforit.Monitor
+	action   string
+	signal   os.Signal
+	failures int
+	grace    time.Duration
+	logger   *slog.Logger
+}
+
+// run starts the post-command and reacts once the dependencies fail
+// the configured number of consecutive probes. It returns the exit code
+// waitforit should exit with.
+func (s *supervisor) run() int { // nolint gocyclo
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates := make(chan struct{}, 1)
+	s.monitor.OnUpdate = func(waitforit.TargetStatus) {
+		select {
+		case updates <- struct{}{}:
+		default:
+		}
+	}
+	go s.monitor.Run(ctx)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, forwardedSignals...)
+	defer signal.Stop(sigs)
+
+	cmd, exited, err := startCommand(s.args)
+	if err != nil {
+		log.Print(err)
+		return startFailureCode(err)
+	}
+
+	// the loss is handled once until the dependencies are healthy again
+	handled := false
+	for {
+		select {
+		case sig := <-sigs:
+			forwardSignal(cmd, sig)
+		case <-exited:
+			return exitCode(cmd.ProcessState)
+		case <-updates:
+			if s.monitor.Healthy(s.failures) {
+				handled = false
+				continue
+			}
+			if handled {
+				continue
+			}
+			handled = true
+
+			s.logger.Warn("Dependency lost", "action", s.action, "signal", s.signal.String())
+			switch s.action {
+			case superviseSignal:
+				cmd.Process.Signal(s.signal) // nolint
+			case superviseExit:
+				s.stopCommand(cmd, exited, sigs)
+				return exitDependencyLost
+			case superviseRestart:
+				s.stopCommand(cmd, exited, sigs)
+				if sig, ok := s.waitRecovery(updates, sigs); !ok {
+					return signalExitCode(sig)
+				}
+
+				s.logger.Info("Dependencies recovered, restarting post command")
+				if cmd, exited, err = startCommand(s.args); err != nil {
+					log.Print(err)
+					return startFailureCode(err)
+				}
+				handled = false
+			}
+		}
+	}
+}
+
+// waitRecovery blocks until the monitor reports every dependency as ready.
+// It returns false with the signal received by waitforit meanwhile.
+func (s *supervisor) waitRecovery(updates <-chan struct{}, sigs <-chan os.Signal) (os.Signal, bool) {
+	for !s.monitor.Ready() {
+		select {
+		case sig := <-sigs:
+			return sig, false
+		case <-updates:
+		}
+	}
+
+	return nil, true
+}
+
+// stopCommand sends the signal to the command and waits until it exits,
+// killing it once the grace period is over. Signals received by waitforit
+// meanwhile are still forwarded to the command.
+func (s *supervisor) stopCommand(cmd *exec.Cmd, exited <-chan struct{}, sigs <-chan os.Signal) {
+	cmd.Process.Signal(s.signal) // nolint
+
+	grace := time.NewTimer(s.grace)
+	defer grace.Stop()
+
+	for {
+		select {
+		case <-exited:
+			return
+		case sig := <-sigs:
+			forwardSignal(cmd, sig)
+		case <-grace.C:
+			s.logger.Warn("Post command did not stop in time, killing it", "grace", s.grace)
+			cmd.Process.Kill() // nolint
+		}
+	}
+}
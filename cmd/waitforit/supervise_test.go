@@ -0,0 +1,98 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/maxcnunes/waitforit"
+)
+
+func TestSupervisor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a unix shell")
+	}
+
+	logger := slog.New(slog.DiscardHandler)
+
+	testCases := []struct {
+		title  string
+		action string
+		script string
+		code   int
+	}{
+		{
+			title:  "exit with a distinct code once the dependency is lost",
+			action: superviseExit,
+			script: `trap "exit 0" TERM; while :; do sleep 0.05; done`,
+			code:   exitDependencyLost,
+		},
+		{
+			title:  "kill the command ignoring the signal after the grace period",
+			action: superviseExit,
+			script: `trap "" TERM; while :; do sleep 0.05; done`,
+			code:   exitDependencyLost,
+		},
+		{
+			title:  "send the signal once the dependency is lost",
+			action: superviseSignal,
+			script: `trap "exit 5" TERM; while :; do sleep 0.05; done`,
+			code:   5,
+		},
+		{
+			title:  "restart once the dependency recovers",
+			action: superviseRestart,
+			script: `echo start >> "$0"; [ $(wc -l < "$0") -ge 2 ] && exit 7; trap "exit 0" TERM; while :; do sleep 0.05; done`,
+			code:   7,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr := l.Addr().String()
+
+			m, err := waitforit.NewMonitor(waitforit.FileConfig{
+				Configs: []waitforit.Config{{Address: "tcp://" + addr, Retry: 50}},
+			}, logger)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sv := &supervisor{
+				args:     []string{"sh", "-c", tc.script, filepath.Join(t.TempDir(), "starts")},
+				monitor:  m,
+				action:   tc.action,
+				signal:   syscall.SIGTERM,
+				failures: 2,
+				grace:    200 * time.Millisecond,
+				logger:   logger,
+			}
+
+			// lose the dependency for a while and bring it back
+			restored := make(chan net.Listener, 1)
+			go func() {
+				time.Sleep(300 * time.Millisecond)
+				l.Close() // nolint
+				time.Sleep(500 * time.Millisecond)
+				l, _ := net.Listen("tcp", addr)
+				restored <- l
+			}()
+
+			if code := sv.run(); code != tc.code {
+				t.Errorf("Expected exit code %d, got %d", tc.code, code)
+			}
+
+			if l := <-restored; l != nil {
+				l.Close() // nolint
+			}
+		})
+	}
+}
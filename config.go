@@ -0,0 +1,156 @@
+package waitforit
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Expected states of the target supported by Config.Expect
+const (
+	ExpectUp   = "up"
+	ExpectDown = "down"
+)
+
+// Config describes the connection config
+type Config struct {
+	Name      string   `json:"name"`
+	DependsOn []string `json:"dependsOn"`
+
+	Protocol string            `json:"proto"`
+	Host     string            `json:"host"`
+	Port     int               `json:"port"`
+	Address  string            `json:"address"`
+	Status   StatusCodes       `json:"status"`
+	Insecure bool              `json:"insecure"`
+	Timeout  int               `json:"timeout"`
+	Retry    int               `json:"retry"`
+	Headers  map[string]string `json:"headers"`
+
+	RetryPolicy     string  `json:"retryPolicy"`
+	RetryMax        int     `json:"retryMax"`
+	RetryMultiplier float64 `json:"retryMultiplier"`
+	AttemptTimeout  int     `json:"attemptTimeout"`
+
+	SuccessThreshold int `json:"successThreshold"`
+	StableFor        int `json:"stableFor"`
+
+	Expect string `json:"expect"`
+
+	Payload         string `json:"payload"`
+	PayloadHex      string `json:"payloadHex"`
+	ResponsePattern string `json:"responsePattern"`
+
+	Query string `json:"query"`
+
+	BodyContains string `json:"bodyContains"`
+	BodyMatches  string `json:"bodyMatches"`
+	JSONPath     string `json:"jsonPath"`
+
+	Method      string `json:"method"`
+	Body        string `json:"body"`
+	BodyFile    string `json:"bodyFile"`
+	ContentType string `json:"contentType"`
+
+	CAFile     string `json:"caFile"`
+	CertFile   string `json:"certFile"`
+	KeyFile    string `json:"keyFile"`
+	ServerName string `json:"serverName"`
+
+	CertHostname string `json:"certHostname"`
+	CertMinDays  int    `json:"certMinDays"`
+	CertIssuer   string `json:"certIssuer"`
+}
+
+// FileConfig describes the structure of the config json file
+type FileConfig struct {
+	Configs []Config
+	Groups  []Group
+}
+
+// validate checks the config values that can be verified
+// before any attempt is made to reach the target
+func (c *Config) validate() error {
+	if c.Expect != "" && c.Expect != ExpectUp && c.Expect != ExpectDown {
+		return fmt.Errorf("Invalid expect %q: must be %s or %s", c.Expect, ExpectUp, ExpectDown)
+	}
+
+	if err := validateRetryPolicy(c.RetryPolicy); err != nil {
+		return err
+	}
+
+	if !c.Status.isDefault() {
+		if _, err := c.Status.parse(); err != nil {
+			return err
+		}
+	}
+
+	if c.Payload != "" && c.PayloadHex != "" {
+		return errors.New("Only one of payload or payloadHex can be provided")
+	}
+
+	if _, err := hex.DecodeString(c.PayloadHex); err != nil {
+		return fmt.Errorf("Invalid payloadHex: %v", err)
+	}
+
+	if _, err := regexp.Compile(c.ResponsePattern); err != nil {
+		return fmt.Errorf("Invalid responsePattern: %v", err)
+	}
+
+	if _, err := regexp.Compile(c.BodyMatches); err != nil {
+		return fmt.Errorf("Invalid bodyMatches: %v", err)
+	}
+
+	if c.JSONPath != "" {
+		if _, err := parseJSONPathExpr(c.JSONPath); err != nil {
+			return err
+		}
+	}
+
+	if c.Body != "" && c.BodyFile != "" {
+		return errors.New("Only one of body or bodyFile can be provided")
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("Both certFile and keyFile must be provided")
+	}
+
+	// the files are loaded up front, otherwise a mistake in them would
+	// fail every attempt and be taken as the target being down
+	if _, err := tlsConfig(c); err != nil {
+		return err
+	}
+
+	if c.BodyFile != "" {
+		f, err := os.Open(c.BodyFile)
+		if err != nil {
+			return fmt.Errorf("Error reading body file: %v", err)
+		}
+		f.Close() // nolint
+	}
+
+	return nil
+}
+
+// label identifies the config on messages, by its name when it has one
+func (c *Config) label() string {
+	if c.Name != "" {
+		return c.Name
+	}
+
+	return RedactAddress(c.Address)
+}
+
+// isStable checks if the consecutive successes
+// satisfy the success threshold and the stable period
+func (c *Config) isStable(successes int, upFor time.Duration) bool {
+	threshold := c.SuccessThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	return successes >= threshold && upFor >= time.Duration(c.StableFor)*time.Millisecond
+}
@@ -1,4 +1,4 @@
-package main
+package waitforit
 
 import (
 	"errors"
@@ -7,18 +7,40 @@ import (
 	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 // Connection data
 type Connection struct {
 	NetworkType string
 	URL         *url.URL
+	Config      *Config
+	SocketPath  string
+
+	// index and group identify the config on the list it was built from
+	index int
+	group string
 }
 
 var defaultProtPorts = map[string]string{
 	"http":  "80",
 	"https": "443",
 	"ssh":   "22",
+
+	"postgres":   "5432",
+	"postgresql": "5432",
+	"mysql":      "3306",
+	"mariadb":    "3306",
+	"redis":      "6379",
+	"rediss":     "6379",
+
+	"tls+postgres": "5432",
+}
+
+var schemeNetworkTypes = map[string]string{
+	"udp":       "udp",
+	"unix":      "unix",
+	"http+unix": "unix",
 }
 
 var defaultPortSchemes = map[string]string{
@@ -43,7 +65,14 @@ func BuildConn(cfg *Config) (*Connection, error) { // nolint gocyclo
 		return nil, errors.New("Connection address is empty")
 	}
 
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
 	u, err := url.Parse(address)
+	if err == nil && resolveNetworkType(u) == "unix" {
+		return buildUnixConn(cfg, u)
+	}
 
 	// the url parsing may fail if it is missing the scheme value
 	// so it try again adding a tcp scheme as falback
@@ -67,9 +96,15 @@ func BuildConn(cfg *Config) (*Connection, error) { // nolint gocyclo
 	// resolve default scheme based on the provided port
 	u.Scheme = resolveScheme(u)
 
+	// a tls target has no protocol to tell its default port
+	if u.Scheme == "tls" && u.Port() == "" {
+		return nil, fmt.Errorf("Missing port in tls address: %s", u.Redacted())
+	}
+
 	return &Connection{
-		NetworkType: "tcp",
+		NetworkType: resolveNetworkType(u),
 		URL:         u,
+		Config:      cfg,
 	}, nil
 }
 
@@ -108,3 +143,69 @@ func resolveScheme(u *url.URL) string {
 
 	return u.Scheme
 }
+
+func resolveNetworkType(u *url.URL) string {
+	if nt, ok := schemeNetworkTypes[u.Scheme]; ok {
+		return nt
+	}
+
+	return "tcp"
+}
+
+// buildUnixConn build a connection to a unix domain socket.
+// The socket path is given by the url path (e.g. unix:///var/run/docker.sock)
+// and http checks add the request path after a colon
+// (e.g. http+unix:///var/run/docker.sock:/_ping).
+func buildUnixConn(cfg *Config, u *url.URL) (*Connection, error) {
+	socketPath, _ := splitUnixPath(u.Path)
+	if u.Host != "" || socketPath == "" {
+		return nil, fmt.Errorf("Couldn't parse unix socket address: %s", u)
+	}
+
+	return &Connection{
+		NetworkType: "unix",
+		URL:         u,
+		Config:      cfg,
+		SocketPath:  socketPath,
+	}, nil
+}
+
+// splitUnixPath splits a unix url path into the socket path and the request path
+func splitUnixPath(p string) (socketPath string, requestPath string) {
+	parts := strings.SplitN(p, ":", 2)
+	if len(parts) == 1 || parts[1] == "" {
+		return parts[0], "/"
+	}
+
+	return parts[0], parts[1]
+}
+
+// dialAddress returns the address used to dial the target
+func (c *Connection) dialAddress() string {
+	if c.NetworkType == "unix" {
+		return c.SocketPath
+	}
+
+	return c.URL.Host
+}
+
+// requestURL returns the url used on http checks
+func (c *Connection) requestURL() string {
+	if c.NetworkType != "unix" {
+		return c.URL.String()
+	}
+
+	_, requestPath := splitUnixPath(c.URL.Path)
+	u := url.URL{Scheme: "http", Host: "unix", Path: requestPath, RawQuery: c.URL.RawQuery}
+	return u.String()
+}
+
+// RedactAddress hides the password of the address, even when it is not a valid connection
+func RedactAddress(address string) string {
+	u, err := url.Parse(address)
+	if err != nil || u.User == nil {
+		return address
+	}
+
+	return u.Redacted()
+}
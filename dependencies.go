@@ -0,0 +1,94 @@
+package waitforit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dependencyGraph maps each config index to the indexes of the configs it depends on
+type dependencyGraph [][]int
+
+// buildDependencyGraph resolves the dependsOn names of the configs
+// and makes sure they describe a DAG
+func buildDependencyGraph(confs []Config) (dependencyGraph, error) {
+	names := make(map[string]int)
+	for i, conf := range confs {
+		if conf.Name == "" {
+			continue
+		}
+		if _, ok := names[conf.Name]; ok {
+			return nil, fmt.Errorf("Duplicated config name %q", conf.Name)
+		}
+		names[conf.Name] = i
+	}
+
+	graph := make(dependencyGraph, len(confs))
+	for i, conf := range confs {
+		for _, dep := range conf.DependsOn {
+			j, ok := names[dep]
+			if !ok {
+				return nil, fmt.Errorf("Config %q depends on unknown config %q", conf.label(), dep)
+			}
+			if j == i {
+				return nil, fmt.Errorf("Config %q depends on itself", conf.Name)
+			}
+			graph[i] = append(graph[i], j)
+		}
+	}
+
+	if cycle := graph.findCycle(); cycle != nil {
+		path := make([]string, len(cycle))
+		for i, j := range cycle {
+			path[i] = confs[j].Name
+		}
+		return nil, fmt.Errorf("Dependency cycle: %s", strings.Join(path, " -> "))
+	}
+
+	return graph, nil
+}
+
+// findCycle returns the indexes of a dependency cycle (repeating the first
+// one at the end) or nil when there is none
+func (g dependencyGraph) findCycle() []int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make([]int, len(g))
+	var stack []int
+
+	var visit func(i int) []int
+	visit = func(i int) []int {
+		state[i] = visiting
+		stack = append(stack, i)
+		for _, j := range g[i] {
+			switch state[j] {
+			case visiting:
+				for k, s := range stack {
+					if s == j {
+						return append(append([]int{}, stack[k:]...), j)
+					}
+				}
+			case unvisited:
+				if cycle := visit(j); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+		return nil
+	}
+
+	for i := range g {
+		if state[i] == unvisited {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
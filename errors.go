@@ -0,0 +1,113 @@
+package waitforit
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// TargetError describes a target that never became available
+type TargetError struct {
+	Address string
+	Config  Config
+
+	// Index is the position of the config on its list and Group
+	// the name of the group it belongs to, as in Attempt
+	Index int
+	Group string
+
+	Err      error
+	Attempts int
+	Elapsed  time.Duration
+
+	// BlockedBy is the name of the dependency that did not become
+	// available, in which case the target itself was never dialed
+	BlockedBy string
+}
+
+func (e *TargetError) Error() string {
+	return fmt.Sprintf("%s: %v (%d attempts in %v)", e.Address, e.Err, e.Attempts, e.Elapsed.Round(time.Millisecond))
+}
+
+// Unwrap returns the last error returned while probing the target
+func (e *TargetError) Unwrap() error {
+	return e.Err
+}
+
+// GroupError describes a group that did not reach its quorum
+type GroupError struct {
+	Name     string
+	Required int
+	Ready    int
+	Targets  []*TargetError
+}
+
+func (e *GroupError) Error() string {
+	var b bytes.Buffer
+	e.writeSummary(&b)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (e *GroupError) writeSummary(b *bytes.Buffer) {
+	fmt.Fprintf(b, "group %s: %d of %d required targets are available:\n", e.Name, e.Ready, e.Required)
+	writeTargets(b, e.Targets)
+}
+
+// Unwrap returns the errors of each failed target of the group
+func (e *GroupError) Unwrap() []error {
+	errs := make([]error, len(e.Targets))
+	for i, t := range e.Targets {
+		errs[i] = t
+	}
+	return errs
+}
+
+// DialError aggregates every target and group that failed while dialing multiple configs
+type DialError struct {
+	Targets []*TargetError
+	Groups  []*GroupError
+	Total   int
+}
+
+// Error renders the failed targets as a summary table
+func (e *DialError) Error() string {
+	var b bytes.Buffer
+	if len(e.Targets) > 0 {
+		fmt.Fprintf(&b, "%d of %d targets are not available:\n", len(e.Targets), e.Total)
+		writeTargets(&b, e.Targets)
+	}
+
+	for _, g := range e.Groups {
+		g.writeSummary(&b)
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// writeTargets renders the targets as a table
+func writeTargets(b *bytes.Buffer, targets []*TargetError) {
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tADDRESS\tATTEMPTS\tELAPSED\tERROR")
+	for _, t := range targets {
+		name := t.Config.Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%v\t%v\n", name, t.Address, t.Attempts, t.Elapsed.Round(time.Millisecond), t.Err)
+	}
+	w.Flush() // nolint
+}
+
+// Unwrap returns the errors of each failed target and group
+func (e *DialError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Targets)+len(e.Groups))
+	for _, t := range e.Targets {
+		errs = append(errs, t)
+	}
+	for _, g := range e.Groups {
+		errs = append(errs, g)
+	}
+	return errs
+}
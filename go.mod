@@ -0,0 +1,3 @@
+module github.com/maxcnunes/waitforit
+
+go 1.24
@@ -0,0 +1,212 @@
+package waitforit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Modes supported by Group.Mode
+const (
+	GroupAll     = "all"
+	GroupAny     = "any"
+	GroupAtLeast = "atLeast"
+)
+
+// Group describes configs of a clustered service
+// where only a quorum of them must become available
+type Group struct {
+	Name    string   `json:"name"`
+	Mode    string   `json:"mode"`
+	AtLeast int      `json:"atLeast"`
+	Configs []Config `json:"configs"`
+}
+
+// required validates the group and returns
+// how many of its configs must become available
+func (g *Group) required() (int, error) {
+	if len(g.Configs) == 0 {
+		return 0, fmt.Errorf("Group %q has no configs", g.Name)
+	}
+
+	for _, conf := range g.Configs {
+		if len(conf.DependsOn) > 0 {
+			return 0, fmt.Errorf("Config %q on group %q cannot have dependencies", conf.label(), g.Name)
+		}
+	}
+
+	switch g.Mode {
+	case "", GroupAll:
+		return len(g.Configs), nil
+	case GroupAny:
+		return 1, nil
+	case GroupAtLeast:
+		if g.AtLeast < 1 || g.AtLeast > len(g.Configs) {
+			return 0, fmt.Errorf("Invalid atLeast %d on group %q: must be between 1 and %d", g.AtLeast, g.Name, len(g.Configs))
+		}
+		return g.AtLeast, nil
+	}
+
+	return 0, fmt.Errorf("Invalid mode %q on group %q: must be %s, %s or %s", g.Mode, g.Name, GroupAll, GroupAny, GroupAtLeast)
+}
+
+// groupsRequired validates the groups of a file, which must have unique
+// names, and returns how many configs of each one must become available
+func groupsRequired(groups []Group) ([]int, error) {
+	required := make([]int, len(groups))
+	names := make(map[string]bool)
+	for i := range groups {
+		name := groups[i].Name
+		if name == "" {
+			return nil, fmt.Errorf("Group %d has no name", i+1)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("Duplicate group %q", name)
+		}
+		names[name] = true
+
+		var err error
+		if required[i], err = groups[i].required(); err != nil {
+			return nil, err
+		}
+	}
+
+	return required, nil
+}
+
+// DialGroup dial the configs of the group at same time and succeeds
+// as soon as the required number of them are available, cancelling the rest.
+// When the quorum is not reached the returned error is a *GroupError.
+func DialGroup(ctx context.Context, group Group, logger *slog.Logger) error {
+	logger = loggerOrDiscard(logger)
+	required, err := group.required()
+	if err != nil {
+		return err
+	}
+
+	conns, err := buildConns(group.Configs, group.Name)
+	if err != nil {
+		return err
+	}
+
+	return dialGroup(ctx, group, conns, required, logger)
+}
+
+func dialGroup(ctx context.Context, group Group, conns []*Connection, required int, logger *slog.Logger) error {
+	logger = logger.With("group", group.Name)
+	groupCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan error, len(conns))
+	for _, conn := range conns {
+		go func(conn *Connection) {
+			results <- DialConn(groupCtx, conn, logger)
+		}(conn)
+	}
+
+	ready := 0
+	var failed []*TargetError
+	for pending := len(conns); pending > 0; pending-- {
+		err := <-results
+
+		// once decided the remaining probes are cancelled and their errors ignored
+		decided := ready >= required || ready+pending < required
+		if decided {
+			continue
+		}
+
+		if err == nil {
+			ready++
+		} else {
+			var te *TargetError
+			if !errors.As(err, &te) {
+				return err
+			}
+			failed = append(failed, te)
+		}
+
+		if ready >= required {
+			logger.Info("Group ready", "ready", ready, "required", required)
+			cancel()
+		} else if ready+pending-1 < required {
+			cancel()
+		}
+	}
+
+	if ready >= required {
+		return nil
+	}
+
+	return &GroupError{
+		Name:     group.Name,
+		Required: required,
+		Ready:    ready,
+		Targets:  failed,
+	}
+}
+
+// DialFileConfig dial the configs and groups of the file at same time.
+// Invalid configs or groups fail right away, otherwise every failure
+// is reported in a *DialError.
+func DialFileConfig(ctx context.Context, fc FileConfig, logger *slog.Logger) error {
+	logger = loggerOrDiscard(logger)
+	conns, err := buildConns(fc.Configs, "")
+	if err != nil {
+		return err
+	}
+
+	deps, err := buildDependencyGraph(fc.Configs)
+	if err != nil {
+		return err
+	}
+
+	required, err := groupsRequired(fc.Groups)
+	if err != nil {
+		return err
+	}
+
+	groupConns := make([][]*Connection, len(fc.Groups))
+	for i := range fc.Groups {
+		if groupConns[i], err = buildConns(fc.Groups[i].Configs, fc.Groups[i].Name); err != nil {
+			return err
+		}
+	}
+
+	errs := make([]error, len(fc.Groups)+1)
+	var wg sync.WaitGroup
+	wg.Add(len(errs))
+	go func() {
+		defer wg.Done()
+		errs[0] = dialConns(ctx, conns, deps, logger)
+	}()
+	for i := range fc.Groups {
+		go func(i int) {
+			defer wg.Done()
+			errs[i+1] = dialGroup(ctx, fc.Groups[i], groupConns[i], required[i], logger)
+		}(i)
+	}
+	wg.Wait()
+
+	result := &DialError{Total: len(fc.Configs)}
+	for _, err := range errs {
+		var de *DialError
+		var ge *GroupError
+		switch {
+		case err == nil:
+		case errors.As(err, &de):
+			result.Targets = append(result.Targets, de.Targets...)
+		case errors.As(err, &ge):
+			result.Groups = append(result.Groups, ge)
+		default:
+			return err
+		}
+	}
+
+	if len(result.Targets) > 0 || len(result.Groups) > 0 {
+		return result
+	}
+
+	return nil
+}
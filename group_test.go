@@ -0,0 +1,224 @@
+package waitforit_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/maxcnunes/waitforit"
+)
+
+func TestDialGroup(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	var up []string
+	for i := 0; i < 2; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close() // nolint
+		up = append(up, "tcp://"+l.Addr().String())
+	}
+
+	configs := []Config{
+		{Name: "node-1", Address: up[0], Timeout: 10, Retry: 200},
+		{Name: "node-2", Address: up[1], Timeout: 10, Retry: 200},
+		{Name: "node-3", Address: "localhost:8087", Timeout: 1, Retry: 200},
+	}
+
+	testCases := []struct {
+		title    string
+		group    Group
+		finishOk bool
+	}{
+		{
+			title:    "Should succeed when any config is available",
+			group:    Group{Name: "cluster", Mode: GroupAny, Configs: configs},
+			finishOk: true,
+		},
+		{
+			title:    "Should succeed when the quorum is available",
+			group:    Group{Name: "cluster", Mode: GroupAtLeast, AtLeast: 2, Configs: configs},
+			finishOk: true,
+		},
+		{
+			title:    "Should fail when the quorum is not available",
+			group:    Group{Name: "cluster", Mode: GroupAtLeast, AtLeast: 3, Configs: configs},
+			finishOk: false,
+		},
+		{
+			title:    "Should fail when not all configs are available",
+			group:    Group{Name: "cluster", Mode: GroupAll, Configs: configs},
+			finishOk: false,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			err := DialGroup(context.Background(), v.group, logger)
+			if err != nil && v.finishOk {
+				t.Errorf("Expected to connect successfully %s. But got error %v.", v.group.Name, err)
+			}
+
+			if err == nil && !v.finishOk {
+				t.Errorf("Expected to not connect successfully %s.", v.group.Name)
+			}
+		})
+	}
+}
+
+func TestDialGroupCancelRemaining(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close() // nolint
+
+	group := Group{
+		Name: "sentinels",
+		Mode: GroupAny,
+		Configs: []Config{
+			{Address: "localhost:8087", Timeout: 30, Retry: 200},
+			{Address: "tcp://" + l.Addr().String(), Timeout: 30, Retry: 200},
+			{Address: "localhost:8088", Timeout: 30, Retry: 200},
+		},
+	}
+
+	start := time.Now()
+	if err := DialGroup(context.Background(), group, logger); err != nil {
+		t.Fatalf("Expected to connect successfully %s. But got error %v.", group.Name, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the remaining probes to be cancelled, took %v", elapsed)
+	}
+}
+
+func TestDialGroupInvalid(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	configs := []Config{{Address: "localhost:8087"}, {Address: "localhost:8088"}}
+
+	testCases := []struct {
+		title string
+		group Group
+		err   string
+	}{
+		{
+			title: "Should fail with an unknown mode",
+			group: Group{Name: "cluster", Mode: "most", Configs: configs},
+			err:   `Invalid mode "most" on group "cluster": must be all, any or atLeast`,
+		},
+		{
+			title: "Should fail when atLeast is above the number of configs",
+			group: Group{Name: "cluster", Mode: GroupAtLeast, AtLeast: 3, Configs: configs},
+			err:   `Invalid atLeast 3 on group "cluster": must be between 1 and 2`,
+		},
+		{
+			title: "Should fail without configs",
+			group: Group{Name: "cluster", Mode: GroupAny},
+			err:   `Group "cluster" has no configs`,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			err := DialGroup(context.Background(), v.group, logger)
+			if err == nil {
+				t.Fatal("Expected invalid group to fail")
+			}
+			assertEqual(t, "error", err.Error(), v.err)
+		})
+	}
+}
+
+func TestDialFileConfig(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close() // nolint
+	up := "tcp://" + l.Addr().String()
+
+	fc := FileConfig{
+		Configs: []Config{
+			{Name: "api", Address: up, Timeout: 1, Retry: 200},
+		},
+		Groups: []Group{
+			{
+				Name:    "brokers",
+				Mode:    GroupAtLeast,
+				AtLeast: 2,
+				Configs: []Config{
+					{Name: "broker-1", Address: up, Timeout: 1, Retry: 200},
+					{Name: "broker-2", Address: "localhost:8087", Timeout: 1, Retry: 200},
+				},
+			},
+		},
+	}
+
+	err = DialFileConfig(context.Background(), fc, logger)
+
+	var de *DialError
+	if !errors.As(err, &de) {
+		t.Fatalf("Expected a *DialError, got %#v", err)
+	}
+
+	assertEqual(t, "failed targets", len(de.Targets), 0)
+	assertEqual(t, "failed groups", len(de.Groups), 1)
+	assertEqual(t, "ready", de.Groups[0].Ready, 1)
+	assertEqual(t, "failed group targets", len(de.Groups[0].Targets), 1)
+	assertEqual(t, "failed target index", de.Groups[0].Targets[0].Index, 1)
+	assertEqual(t, "failed target group", de.Groups[0].Targets[0].Group, "brokers")
+
+	if !strings.Contains(err.Error(), "group brokers: 1 of 2 required targets are available") {
+		t.Errorf("Unexpected summary %q", err.Error())
+	}
+
+	fc.Groups[0].Mode = GroupAny
+	if err := DialFileConfig(context.Background(), fc, logger); err != nil {
+		t.Errorf("Expected to connect successfully. But got error %v.", err)
+	}
+}
+
+func TestDialFileConfigInvalidGroups(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	configs := []Config{{Address: "localhost:8087"}}
+
+	testCases := []struct {
+		title  string
+		groups []Group
+		err    string
+	}{
+		{
+			title:  "Should fail when a group has no name",
+			groups: []Group{{Name: "cluster", Configs: configs}, {Configs: configs}},
+			err:    "Group 2 has no name",
+		},
+		{
+			title:  "Should fail when group names are repeated",
+			groups: []Group{{Name: "cluster", Configs: configs}, {Name: "cluster", Configs: configs}},
+			err:    `Duplicate group "cluster"`,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			err := DialFileConfig(context.Background(), FileConfig{Groups: v.groups}, logger)
+			if err == nil {
+				t.Fatal("Expected invalid groups to fail")
+			}
+			assertEqual(t, "error", err.Error(), v.err)
+		})
+	}
+}
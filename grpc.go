@@ -0,0 +1,173 @@
+package waitforit
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterProber("grpc", ProberFunc(pingGRPC))
+	RegisterProber("grpcs", ProberFunc(pingGRPC))
+}
+
+const grpcHealthCheckPath = "/grpc.health.v1.Health/Check"
+
+// grpcServingStatus are the values of grpc.health.v1.HealthCheckResponse.ServingStatus
+var grpcServingStatus = map[uint64]string{
+	0: "UNKNOWN",
+	1: "SERVING",
+	2: "NOT_SERVING",
+	3: "SERVICE_UNKNOWN",
+}
+
+// pingGRPC check if the server reports SERVING through the standard gRPC
+// health checking protocol. The service name is given by the url path
+// and the config headers are sent as request metadata.
+func pingGRPC(ctx context.Context, conn *Connection) error {
+	conf := conn.Config
+
+	var protocols http.Protocols
+	transport := &http.Transport{Protocols: &protocols}
+	scheme := "http"
+	if conn.URL.Scheme == "grpcs" {
+		scheme = "https"
+		protocols.SetHTTP2(true)
+		tc, err := tlsConfig(conf)
+		if err != nil {
+			return err
+		}
+		transport.TLSClientConfig = tc
+	} else {
+		protocols.SetUnencryptedHTTP2(true)
+	}
+
+	client := &http.Client{Transport: transport}
+	defer client.CloseIdleConnections()
+
+	service := strings.TrimPrefix(conn.URL.Path, "/")
+	u := url.URL{Scheme: scheme, Host: conn.URL.Host, Path: grpcHealthCheckPath}
+	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), bytes.NewReader(grpcHealthCheckRequest(service)))
+	if err != nil {
+		return fmt.Errorf("Error creating request: %v", err)
+	}
+
+	for k, v := range conf.Headers {
+		req.Header.Add(k, v)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("grpc: unexpected HTTP status %q", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// errors may be sent as trailers-only responses (in the headers)
+	grpcStatus := resp.Trailer.Get("Grpc-Status")
+	grpcMessage := resp.Trailer.Get("Grpc-Message")
+	if grpcStatus == "" {
+		grpcStatus = resp.Header.Get("Grpc-Status")
+		grpcMessage = resp.Header.Get("Grpc-Message")
+	}
+
+	if grpcStatus != "0" {
+		msg, _ := url.PathUnescape(grpcMessage)
+		return fmt.Errorf("grpc: health check failed with status %s: %s", grpcStatus, msg)
+	}
+
+	status, err := grpcHealthCheckStatus(body)
+	if err != nil {
+		return err
+	}
+
+	if status != 1 {
+		name, ok := grpcServingStatus[status]
+		if !ok {
+			name = strconv.FormatUint(status, 10)
+		}
+		return fmt.Errorf("grpc: service is %s", name)
+	}
+
+	return nil
+}
+
+// grpcHealthCheckRequest encodes a length-prefixed HealthCheckRequest message
+func grpcHealthCheckRequest(service string) []byte {
+	var msg []byte
+	if service != "" {
+		// field 1 (service), wire type 2 (length delimited)
+		msg = append(msg, 0x0a)
+		msg = binary.AppendUvarint(msg, uint64(len(service)))
+		msg = append(msg, service...)
+	}
+
+	frame := []byte{0}
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(msg)))
+	return append(frame, msg...)
+}
+
+// grpcHealthCheckStatus decodes the status of a length-prefixed HealthCheckResponse message
+func grpcHealthCheckStatus(frame []byte) (uint64, error) {
+	if len(frame) < 5 {
+		return 0, errors.New("grpc: empty health check response")
+	}
+
+	if frame[0] != 0 {
+		return 0, errors.New("grpc: compressed responses are not supported")
+	}
+
+	n := binary.BigEndian.Uint32(frame[1:5])
+	if int(n) > len(frame)-5 {
+		return 0, errors.New("grpc: truncated health check response")
+	}
+
+	msg := frame[5 : 5+n]
+	var status uint64
+	for len(msg) > 0 {
+		key, k := binary.Uvarint(msg)
+		if k <= 0 {
+			return 0, errors.New("grpc: invalid health check response")
+		}
+		msg = msg[k:]
+
+		switch key & 7 {
+		case 0:
+			v, k := binary.Uvarint(msg)
+			if k <= 0 {
+				return 0, errors.New("grpc: invalid health check response")
+			}
+			msg = msg[k:]
+			if key>>3 == 1 {
+				status = v
+			}
+		case 2:
+			l, k := binary.Uvarint(msg)
+			if k <= 0 || uint64(len(msg)-k) < l {
+				return 0, errors.New("grpc: invalid health check response")
+			}
+			msg = msg[k+int(l):]
+		default:
+			return 0, fmt.Errorf("grpc: unexpected wire type %d", key&7)
+		}
+	}
+
+	return status, nil
+}
@@ -0,0 +1,133 @@
+package waitforit_test
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/maxcnunes/waitforit"
+)
+
+// grpcHealthHandler answers the grpc health checking protocol
+// with the status registered for the requested service
+func grpcHealthHandler(statuses map[string]byte, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/grpc.health.v1.Health/Check" || r.ProtoMajor != 2 {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/grpc")
+
+		if token != "" && r.Header.Get("Authorization") != token {
+			w.Header().Set("Grpc-Status", "16")
+			w.Header().Set("Grpc-Message", "unauthenticated")
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		service := ""
+		if len(body) > 7 {
+			service = string(body[7:])
+		}
+
+		status, ok := statuses[service]
+		if !ok {
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "unknown service")
+			return
+		}
+
+		msg := []byte{0x08, status}
+		frame := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(msg)))
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write(append(frame, msg...)) // nolint
+		w.Header().Set("Grpc-Status", "0")
+	})
+}
+
+func TestPingGRPC(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	statuses := map[string]byte{"": 1, "app.Users": 1, "app.Orders": 2}
+
+	h2c := httptest.NewUnstartedServer(grpcHealthHandler(statuses, "Bearer token"))
+	h2c.Config.Protocols = new(http.Protocols)
+	h2c.Config.Protocols.SetUnencryptedHTTP2(true)
+	h2c.Start()
+	defer h2c.Close()
+
+	h2 := httptest.NewUnstartedServer(grpcHealthHandler(statuses, ""))
+	h2.EnableHTTP2 = true
+	h2.StartTLS()
+	defer h2.Close()
+
+	h2cHost := strings.TrimPrefix(h2c.URL, "http://")
+	h2Host := strings.TrimPrefix(h2.URL, "https://")
+	auth := map[string]string{"Authorization": "Bearer token"}
+
+	testCases := []struct {
+		title    string
+		address  string
+		headers  map[string]string
+		finishOk bool
+	}{
+		{
+			title:    "Should succeed when the server is SERVING",
+			address:  "grpc://" + h2cHost,
+			headers:  auth,
+			finishOk: true,
+		},
+		{
+			title:    "Should succeed when the service is SERVING",
+			address:  "grpc://" + h2cHost + "/app.Users",
+			headers:  auth,
+			finishOk: true,
+		},
+		{
+			title:    "Should fail when the service is NOT_SERVING",
+			address:  "grpc://" + h2cHost + "/app.Orders",
+			headers:  auth,
+			finishOk: false,
+		},
+		{
+			title:    "Should fail when the service is unknown",
+			address:  "grpc://" + h2cHost + "/app.Missing",
+			headers:  auth,
+			finishOk: false,
+		},
+		{
+			title:    "Should fail when the metadata is missing",
+			address:  "grpc://" + h2cHost,
+			finishOk: false,
+		},
+		{
+			title:    "Should succeed over TLS",
+			address:  "grpcs://" + h2Host + "/app.Users",
+			finishOk: true,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			conn, err := BuildConn(&Config{Address: v.address, Headers: v.headers, Insecure: true, Timeout: 1, Retry: 200})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = DialConn(context.Background(), conn, logger)
+			if err != nil && v.finishOk {
+				t.Errorf("Expected to connect successfully %s. But got error %v.", v.address, err)
+			}
+
+			if err == nil && !v.finishOk {
+				t.Errorf("Expected to not connect successfully %s.", v.address)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package waitforit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// TargetStatus describes the last probe of a monitored target
+type TargetStatus struct {
+	Name    string
+	Group   string
+	Address string
+
+	// Up reports if the target is in the expected state, respecting
+	// the success threshold and stable period of its config
+	Up bool
+
+	// Successes and Failures count the consecutive probes in each state
+	Successes int
+	Failures  int
+
+	Err       error
+	Latency   time.Duration
+	CheckedAt time.Time
+}
+
+type monitorTarget struct {
+	conn    *Connection
+	group   int
+	status  TargetStatus
+	upSince time.Time
+}
+
+type monitorGroup struct {
+	name     string
+	required int
+}
+
+// Monitor keeps probing the configs and groups of a file,
+// tracking the status of each target until its context is done
+type Monitor struct {
+	// OnUpdate is called after every probe with the updated status of the target
+	OnUpdate func(TargetStatus)
+
+	logger  *slog.Logger
+	targets []*monitorTarget
+	groups  []monitorGroup
+
+	mu sync.RWMutex
+}
+
+// NewMonitor validates the configs and groups of the file and creates a monitor for them
+func NewMonitor(fc FileConfig, logger *slog.Logger) (*Monitor, error) {
+	m := &Monitor{logger: loggerOrDiscard(logger)}
+
+	conns, err := buildConns(fc.Configs, "")
+	if err != nil {
+		return nil, err
+	}
+	m.addTargets(conns, -1)
+
+	required, err := groupsRequired(fc.Groups)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range fc.Groups {
+		conns, err := buildConns(fc.Groups[i].Configs, fc.Groups[i].Name)
+		if err != nil {
+			return nil, err
+		}
+
+		m.groups = append(m.groups, monitorGroup{name: fc.Groups[i].Name, required: required[i]})
+		m.addTargets(conns, i)
+	}
+
+	return m, nil
+}
+
+func (m *Monitor) addTargets(conns []*Connection, group int) {
+	for _, conn := range conns {
+		t := &monitorTarget{conn: conn, group: group}
+		t.status.Name = conn.Config.Name
+		t.status.Address = conn.URL.Redacted()
+		if group >= 0 {
+			t.status.Group = m.groups[group].name
+		}
+		m.targets = append(m.targets, t)
+	}
+}
+
+// Run probes every target until the context is done
+func (m *Monitor) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, t := range m.targets {
+		wg.Add(1)
+		go func(t *monitorTarget) {
+			defer wg.Done()
+			m.watch(ctx, t)
+		}(t)
+	}
+	wg.Wait()
+}
+
+func (m *Monitor) watch(ctx context.Context, t *monitorTarget) {
+	conf := t.conn.Config
+	prober := LookupProber(t.conn.URL.Scheme)
+
+	interval := conf.retryInterval()
+
+	for {
+		start := time.Now()
+		err := probeOnce(ctx, prober, t.conn)
+		if ctx.Err() != nil {
+			return
+		}
+
+		m.update(t, conf.expected(err), time.Since(start))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (m *Monitor) update(t *monitorTarget, err error, latency time.Duration) {
+	m.mu.Lock()
+	s := &t.status
+	wasUp := s.Up
+	s.Err = err
+	s.Latency = latency
+	s.CheckedAt = time.Now()
+	if err == nil {
+		if s.Successes == 0 {
+			t.upSince = s.CheckedAt
+		}
+		s.Successes++
+		s.Failures = 0
+		s.Up = t.conn.Config.isStable(s.Successes, s.CheckedAt.Sub(t.upSince))
+	} else {
+		s.Successes = 0
+		s.Failures++
+		s.Up = false
+	}
+	status := *s
+	m.mu.Unlock()
+
+	logger := m.logger.With(targetAttrs(t.conn)...)
+	if status.Group != "" {
+		logger = logger.With("group", status.Group)
+	}
+
+	// state changes are warned, the following probes are only debugged
+	switch {
+	case err != nil && status.Failures == 1:
+		logger.Warn("Down", "latency", latency, "error", err)
+	case err != nil:
+		logger.Debug("Down", "failures", status.Failures, "latency", latency, "error", err)
+	case status.Up && !wasUp:
+		logger.Info("Up", "latency", latency)
+	default:
+		logger.Debug("Up", "successes", status.Successes, "latency", latency)
+	}
+
+	if m.OnUpdate != nil {
+		m.OnUpdate(status)
+	}
+}
+
+// Status returns the current status of every target
+func (m *Monitor) Status() []TargetStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	statuses := make([]TargetStatus, len(m.targets))
+	for i, t := range m.targets {
+		statuses[i] = t.status
+	}
+	return statuses
+}
+
+// Ready reports if every config is up and every group has its quorum up
+func (m *Monitor) Ready() bool {
+	return m.satisfied(func(s TargetStatus) bool {
+		return s.Up
+	})
+}
+
+// Healthy reports if no config and no group quorum has failed
+// the given number of consecutive probes. Targets not probed yet are healthy.
+func (m *Monitor) Healthy(failures int) bool {
+	return m.satisfied(func(s TargetStatus) bool {
+		return s.Failures < failures
+	})
+}
+
+// satisfied checks the condition on every config and on the quorum of every group
+func (m *Monitor) satisfied(ok func(TargetStatus) bool) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	groupOk := make([]int, len(m.groups))
+	for _, t := range m.targets {
+		switch {
+		case !ok(t.status) && t.group < 0:
+			return false
+		case ok(t.status) && t.group >= 0:
+			groupOk[t.group]++
+		}
+	}
+
+	for i, g := range m.groups {
+		if groupOk[i] < g.required {
+			return false
+		}
+	}
+
+	return true
+}
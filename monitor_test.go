@@ -0,0 +1,90 @@
+package waitforit_test
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/maxcnunes/waitforit"
+)
+
+func TestMonitor(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close() // nolint
+	up := "tcp://" + l.Addr().String()
+
+	fc := FileConfig{
+		Configs: []Config{
+			{Name: "api", Address: up, Retry: 50, SuccessThreshold: 2},
+		},
+		Groups: []Group{
+			{
+				Name: "cluster",
+				Mode: GroupAny,
+				Configs: []Config{
+					{Name: "node-1", Address: up, Retry: 50},
+					{Name: "node-2", Address: "localhost:8087", Retry: 50},
+				},
+			},
+		},
+	}
+
+	m, err := NewMonitor(fc, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !m.Healthy(1) || m.Ready() {
+		t.Fatal("Expected targets not probed yet to be healthy but not ready")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	waitFor(t, "ready", m.Ready)
+	assertEqual(t, "healthy", m.Healthy(1), true)
+
+	statuses := m.Status()
+	assertEqual(t, "targets", len(statuses), 3)
+	assertEqual(t, "group", statuses[2].Group, "cluster")
+	assertEqual(t, "node-2 up", statuses[2].Up, false)
+	if statuses[2].Err == nil {
+		t.Error("Expected node-2 to report its last error")
+	}
+
+	l.Close() // nolint
+	waitFor(t, "unhealthy", func() bool { return !m.Healthy(2) })
+	assertEqual(t, "ready", m.Ready(), false)
+}
+
+func TestMonitorInvalidConfig(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	if _, err := NewMonitor(FileConfig{Configs: []Config{{Timeout: 30}}}, logger); err == nil {
+		t.Error("Expected invalid config to fail")
+	}
+
+	if _, err := NewMonitor(FileConfig{Groups: []Group{{Name: "cluster"}}}, logger); err == nil {
+		t.Error("Expected invalid group to fail")
+	}
+}
+
+func waitFor(t *testing.T, state string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected monitor to become %s", state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,359 @@
+package waitforit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" // nolint gosec
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterProber("mysql", ProberFunc(pingMySQL))
+	RegisterProber("mariadb", ProberFunc(pingMySQL))
+}
+
+const (
+	mysqlClientLongPassword     = 0x00000001
+	mysqlClientConnectWithDB    = 0x00000008
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientSecureConnection = 0x00008000
+	mysqlClientPluginAuth       = 0x00080000
+
+	mysqlComQuit  = 0x01
+	mysqlComQuery = 0x03
+
+	mysqlNativePassword = "mysql_native_password"
+	mysqlCachingSHA2    = "caching_sha2_password"
+
+	mysqlUTF8MB4 = 45
+)
+
+// pingMySQL check if the mysql server accepts connections by reading its
+// handshake, authenticating and, when a query is configured, running it.
+// Error packets (e.g. too many connections) are handled as not ready.
+func pingMySQL(ctx context.Context, conn *Connection) error {
+	c, err := dialTarget(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer c.Close() // nolint
+
+	user := conn.URL.User.Username()
+	if user == "" {
+		user = "root"
+	}
+	password, _ := conn.URL.User.Password()
+	database := strings.TrimPrefix(conn.URL.Path, "/")
+
+	my := &mysqlConn{r: bufio.NewReader(c), w: c}
+	if err := my.handshake(user, password, database); err != nil {
+		return err
+	}
+
+	if conn.Config.Query != "" {
+		if err := my.query(conn.Config.Query); err != nil {
+			return err
+		}
+	}
+
+	my.seq = 0
+	return my.send([]byte{mysqlComQuit})
+}
+
+type mysqlConn struct {
+	r   *bufio.Reader
+	w   io.Writer
+	seq byte
+}
+
+// mysqlError is the error reported by the server in an error packet
+type mysqlError struct {
+	Code    uint16
+	Message string
+}
+
+func (e *mysqlError) Error() string {
+	return fmt.Sprintf("mysql: %s (error %d)", e.Message, e.Code)
+}
+
+func (my *mysqlConn) handshake(user, password, database string) error {
+	packet, err := my.receive()
+	if err != nil {
+		return err
+	}
+
+	if packet[0] == 0xff {
+		return parseMySQLError(packet)
+	}
+
+	if packet[0] != 10 {
+		return fmt.Errorf("mysql: unsupported protocol version %d", packet[0])
+	}
+
+	// skip the server version and connection id
+	pos := bytes.IndexByte(packet[1:], 0) + 1 + 1 + 4
+	if pos+8 > len(packet) {
+		return errors.New("mysql: invalid handshake packet")
+	}
+	scramble := append([]byte{}, packet[pos:pos+8]...)
+
+	// skip the filler, capabilities, charset, status
+	// and auth data length plus the reserved bytes
+	pos += 8 + 1 + 2 + 1 + 2 + 2 + 1 + 10
+	plugin := mysqlNativePassword
+	if pos+12 <= len(packet) {
+		scramble = append(scramble, packet[pos:pos+12]...)
+		pos += 13
+		if end := bytes.IndexByte(packet[pos:], 0); end > 0 {
+			plugin = string(packet[pos : pos+end])
+		} else if pos < len(packet) {
+			plugin = string(packet[pos:])
+		}
+	}
+
+	authData, err := mysqlAuthData(plugin, password, scramble)
+	if err != nil {
+		return err
+	}
+
+	flags := uint32(mysqlClientLongPassword | mysqlClientProtocol41 | mysqlClientSecureConnection | mysqlClientPluginAuth)
+	if database != "" {
+		flags |= mysqlClientConnectWithDB
+	}
+
+	b := binary.LittleEndian.AppendUint32(nil, flags)
+	b = binary.LittleEndian.AppendUint32(b, 1<<24-1)
+	b = append(b, mysqlUTF8MB4)
+	b = append(b, make([]byte, 23)...)
+	b = appendCString(b, user)
+	b = append(b, byte(len(authData)))
+	b = append(b, authData...)
+	if database != "" {
+		b = appendCString(b, database)
+	}
+	b = appendCString(b, plugin)
+
+	if err := my.send(b); err != nil {
+		return err
+	}
+
+	return my.authResult(plugin, password, scramble)
+}
+
+// authResult handles the server responses until the authentication is done
+func (my *mysqlConn) authResult(plugin, password string, scramble []byte) error {
+	for {
+		packet, err := my.receive()
+		if err != nil {
+			return err
+		}
+
+		switch packet[0] {
+		case 0x00:
+			return nil
+		case 0xff:
+			return parseMySQLError(packet)
+		case 0xfe:
+			// auth switch request
+			end := bytes.IndexByte(packet[1:], 0)
+			if end < 0 {
+				return errors.New("mysql: invalid auth switch request")
+			}
+			plugin = string(packet[1 : end+1])
+			scramble = bytes.TrimRight(packet[end+2:], "\x00")
+
+			authData, err := mysqlAuthData(plugin, password, scramble)
+			if err != nil {
+				return err
+			}
+			if err := my.send(authData); err != nil {
+				return err
+			}
+		case 0x01:
+			if plugin != mysqlCachingSHA2 || len(packet) < 2 {
+				return errors.New("mysql: unexpected auth more data packet")
+			}
+
+			switch packet[1] {
+			case 3:
+				// fast auth succeeded, the OK packet comes next
+			case 4:
+				// full authentication requires the server public key
+				// since the password can not be sent in clear text
+				if err := my.send([]byte{2}); err != nil {
+					return err
+				}
+				if err := my.sendEncryptedPassword(password, scramble); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("mysql: unexpected caching_sha2_password state %d", packet[1])
+			}
+		default:
+			return fmt.Errorf("mysql: unexpected packet 0x%02x during authentication", packet[0])
+		}
+	}
+}
+
+func (my *mysqlConn) sendEncryptedPassword(password string, scramble []byte) error {
+	// an auth switch request may come without plugin data
+	if len(scramble) == 0 {
+		return errors.New("mysql: missing scramble to encrypt the password")
+	}
+
+	packet, err := my.receive()
+	if err != nil {
+		return err
+	}
+
+	if packet[0] == 0xff {
+		return parseMySQLError(packet)
+	}
+
+	block, _ := pem.Decode(packet[1:])
+	if block == nil {
+		return errors.New("mysql: invalid server public key")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("mysql: invalid server public key: %v", err)
+	}
+
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("mysql: server public key is not a RSA key")
+	}
+
+	plain := append([]byte(password), 0)
+	for i := range plain {
+		plain[i] ^= scramble[i%len(scramble)]
+	}
+
+	enc, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, rsaPub, plain, nil) // nolint gosec
+	if err != nil {
+		return err
+	}
+
+	return my.send(enc)
+}
+
+func (my *mysqlConn) query(q string) error {
+	my.seq = 0
+	if err := my.send(append([]byte{mysqlComQuery}, q...)); err != nil {
+		return err
+	}
+
+	packet, err := my.receive()
+	if err != nil {
+		return err
+	}
+
+	switch packet[0] {
+	case 0x00:
+		return nil
+	case 0xff:
+		return parseMySQLError(packet)
+	}
+
+	// result set: column definitions and rows are both terminated by an EOF packet
+	for eofs := 0; eofs < 2; {
+		packet, err := my.receive()
+		if err != nil {
+			return err
+		}
+
+		if packet[0] == 0xff {
+			return parseMySQLError(packet)
+		}
+
+		if packet[0] == 0xfe && len(packet) < 9 {
+			eofs++
+		}
+	}
+
+	return nil
+}
+
+func (my *mysqlConn) send(payload []byte) error {
+	header := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), my.seq}
+	my.seq++
+	_, err := my.w.Write(append(header, payload...))
+	return err
+}
+
+func (my *mysqlConn) receive() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(my.r, header[:]); err != nil {
+		return nil, err
+	}
+
+	n := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	if n == 0 {
+		return nil, errors.New("mysql: empty packet")
+	}
+	my.seq = header[3] + 1
+
+	packet := make([]byte, n)
+	if _, err := io.ReadFull(my.r, packet); err != nil {
+		return nil, err
+	}
+
+	return packet, nil
+}
+
+func parseMySQLError(packet []byte) error {
+	if len(packet) < 3 {
+		return errors.New("mysql: invalid error packet")
+	}
+
+	e := &mysqlError{Code: binary.LittleEndian.Uint16(packet[1:3])}
+	msg := packet[3:]
+	// skip the sql state marker and value
+	if len(msg) >= 6 && msg[0] == '#' {
+		msg = msg[6:]
+	}
+	e.Message = string(msg)
+
+	return e
+}
+
+// mysqlAuthData scrambles the password for the given authentication plugin
+func mysqlAuthData(plugin, password string, scramble []byte) ([]byte, error) {
+	if password == "" {
+		return nil, nil
+	}
+
+	switch plugin {
+	case mysqlNativePassword:
+		// SHA1(password) XOR SHA1(scramble + SHA1(SHA1(password)))
+		h1 := sha1.Sum([]byte(password))                                // nolint gosec
+		h2 := sha1.Sum(h1[:])                                           // nolint gosec
+		h3 := sha1.Sum(append(append([]byte{}, scramble...), h2[:]...)) // nolint gosec
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:], nil
+	case mysqlCachingSHA2:
+		// SHA256(password) XOR SHA256(SHA256(SHA256(password)) + scramble)
+		h1 := sha256.Sum256([]byte(password))
+		h2 := sha256.Sum256(h1[:])
+		h3 := sha256.Sum256(append(h2[:], scramble...))
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:], nil
+	}
+
+	return nil, fmt.Errorf("mysql: unsupported authentication plugin %s", plugin)
+}
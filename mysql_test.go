@@ -0,0 +1,286 @@
+package waitforit_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" // nolint gosec
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+
+	. "github.com/maxcnunes/waitforit"
+)
+
+// fakeMySQL implements just enough of the mysql client/server protocol
+// to exercise the handshake performed by the mysql prober
+type fakeMySQL struct {
+	plugin       string
+	password     string
+	fullAuth     bool
+	tooManyConns bool
+	emptySwitch  bool
+	key          *rsa.PrivateKey
+}
+
+var mysqlScramble = []byte("abcdefghijklmnopqrst")
+
+func (f *fakeMySQL) start(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() }) // nolint
+
+	if f.fullAuth || f.emptySwitch {
+		if f.key, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func (f *fakeMySQL) serve(c net.Conn) {
+	defer c.Close() // nolint
+	r := bufio.NewReader(c)
+
+	if f.tooManyConns {
+		mysqlSend(c, 0, mysqlErrorPacket(1040, "Too many connections"))
+		return
+	}
+
+	h := []byte{10}
+	h = append(h, "8.0.0\x00"...)
+	h = binary.LittleEndian.AppendUint32(h, 1)
+	h = append(h, mysqlScramble[:8]...)
+	h = append(h, 0, 0xff, 0xff, 45, 2, 0, 0xff, 0xff, 21)
+	h = append(h, make([]byte, 10)...)
+	h = append(h, mysqlScramble[8:]...)
+	h = append(h, 0)
+	h = append(h, f.plugin+"\x00"...)
+	mysqlSend(c, 0, h)
+
+	seq, resp, err := mysqlReceive(r)
+	if err != nil {
+		return
+	}
+
+	// skip flags, max packet size, charset, filler and user name
+	pos := 32 + bytes.IndexByte(resp[32:], 0) + 1
+	authData := resp[pos+1 : pos+1+int(resp[pos])]
+
+	if f.emptySwitch {
+		// switch to caching_sha2_password without plugin data and require full authentication
+		mysqlSend(c, seq+1, []byte("\xfecaching_sha2_password\x00"))
+		if seq, _, err = mysqlReceive(r); err != nil {
+			return
+		}
+		mysqlSend(c, seq+1, []byte{1, 4})
+		if seq, _, err = mysqlReceive(r); err != nil {
+			return
+		}
+		der, _ := x509.MarshalPKIXPublicKey(&f.key.PublicKey)
+		mysqlSend(c, seq+1, append([]byte{1}, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...))
+		mysqlReceive(r) // nolint
+		return
+	}
+
+	if !f.authenticate(c, r, seq+1, authData) {
+		mysqlSend(c, seq+1, mysqlErrorPacket(1045, "Access denied"))
+		return
+	}
+
+	for {
+		seq, cmd, err := mysqlReceive(r)
+		if err != nil || cmd[0] == 0x01 {
+			return
+		}
+
+		if strings.HasPrefix(string(cmd[1:]), "FAIL") {
+			mysqlSend(c, seq+1, mysqlErrorPacket(1064, "syntax error"))
+			continue
+		}
+
+		// single column result set with a single row
+		mysqlSend(c, seq+1, []byte{1})
+		mysqlSend(c, seq+2, []byte("\x03def\x00\x00\x00\x011\x00\x0c\x3f\x00\x01\x00\x00\x00\x08\x81\x00\x00\x00\x00"))
+		mysqlSend(c, seq+3, []byte{0xfe, 0, 0, 2, 0})
+		mysqlSend(c, seq+4, []byte{1, '1'})
+		mysqlSend(c, seq+5, []byte{0xfe, 0, 0, 2, 0})
+	}
+}
+
+func (f *fakeMySQL) authenticate(c net.Conn, r *bufio.Reader, seq byte, authData []byte) bool {
+	if f.plugin == "mysql_native_password" {
+		h1 := sha1.Sum([]byte(f.password))                                   // nolint gosec
+		h2 := sha1.Sum(h1[:])                                                // nolint gosec
+		h3 := sha1.Sum(append(append([]byte{}, mysqlScramble...), h2[:]...)) // nolint gosec
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		if !bytes.Equal(authData, h1[:]) {
+			return false
+		}
+		mysqlSend(c, seq, []byte{0, 0, 0, 2, 0, 0, 0})
+		return true
+	}
+
+	h1 := sha256.Sum256([]byte(f.password))
+	h2 := sha256.Sum256(h1[:])
+	h3 := sha256.Sum256(append(h2[:], mysqlScramble...))
+	for i := range h1 {
+		h1[i] ^= h3[i]
+	}
+	if !bytes.Equal(authData, h1[:]) {
+		return false
+	}
+
+	if !f.fullAuth {
+		mysqlSend(c, seq, []byte{1, 3})
+		mysqlSend(c, seq+1, []byte{0, 0, 0, 2, 0, 0, 0})
+		return true
+	}
+
+	mysqlSend(c, seq, []byte{1, 4})
+	seq, req, err := mysqlReceive(r)
+	if err != nil || !bytes.Equal(req, []byte{2}) {
+		return false
+	}
+
+	der, _ := x509.MarshalPKIXPublicKey(&f.key.PublicKey)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	mysqlSend(c, seq+1, append([]byte{1}, pemKey...))
+
+	seq, enc, err := mysqlReceive(r)
+	if err != nil {
+		return false
+	}
+	plain, err := rsa.DecryptOAEP(sha1.New(), nil, f.key, enc, nil) // nolint gosec
+	if err != nil {
+		return false
+	}
+	for i := range plain {
+		plain[i] ^= mysqlScramble[i%len(mysqlScramble)]
+	}
+	if string(plain) != f.password+"\x00" {
+		return false
+	}
+
+	mysqlSend(c, seq+1, []byte{0, 0, 0, 2, 0, 0, 0})
+	return true
+}
+
+func mysqlSend(w io.Writer, seq byte, payload []byte) {
+	header := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), seq}
+	w.Write(append(header, payload...)) // nolint
+}
+
+func mysqlReceive(r *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	packet := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+	_, err := io.ReadFull(r, packet)
+	return header[3], packet, err
+}
+
+func mysqlErrorPacket(code uint16, message string) []byte {
+	b := binary.LittleEndian.AppendUint16([]byte{0xff}, code)
+	return append(b, "#HY000"+message...)
+}
+
+func TestPingMySQL(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	testCases := []struct {
+		title    string
+		server   *fakeMySQL
+		userinfo string
+		query    string
+		finishOk bool
+	}{
+		{
+			title:    "Should succeed with mysql_native_password authentication",
+			server:   &fakeMySQL{plugin: "mysql_native_password", password: "secret"},
+			userinfo: "root:secret@",
+			query:    "SELECT 1",
+			finishOk: true,
+		},
+		{
+			title:    "Should fail with wrong mysql_native_password password",
+			server:   &fakeMySQL{plugin: "mysql_native_password", password: "secret"},
+			userinfo: "root:wrong@",
+			finishOk: false,
+		},
+		{
+			title:    "Should succeed with caching_sha2_password fast authentication",
+			server:   &fakeMySQL{plugin: "caching_sha2_password", password: "secret"},
+			userinfo: "app:secret@",
+			finishOk: true,
+		},
+		{
+			title:    "Should succeed with caching_sha2_password full authentication",
+			server:   &fakeMySQL{plugin: "caching_sha2_password", password: "secret", fullAuth: true},
+			userinfo: "app:secret@",
+			query:    "SELECT 1",
+			finishOk: true,
+		},
+		{
+			title:    "Should fail when an auth switch request has no scramble",
+			server:   &fakeMySQL{plugin: "mysql_native_password", password: "secret", emptySwitch: true},
+			userinfo: "app:secret@",
+			finishOk: false,
+		},
+		{
+			title:    "Should fail when the server has too many connections",
+			server:   &fakeMySQL{plugin: "mysql_native_password", tooManyConns: true},
+			finishOk: false,
+		},
+		{
+			title:    "Should fail when the query fails",
+			server:   &fakeMySQL{plugin: "mysql_native_password", password: "secret"},
+			userinfo: "root:secret@",
+			query:    "FAIL",
+			finishOk: false,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			address := "mysql://" + v.userinfo + v.server.start(t) + "/app"
+			conn, err := BuildConn(&Config{Address: address, Query: v.query, Timeout: 1, Retry: 200})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = DialConn(context.Background(), conn, logger)
+			if err != nil && v.finishOk {
+				t.Errorf("Expected to connect successfully %s. But got error %v.", address, err)
+			}
+
+			if err == nil && !v.finishOk {
+				t.Errorf("Expected to not connect successfully %s.", address)
+			}
+		})
+	}
+}
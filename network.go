@@ -1,124 +1,458 @@
-package main
+package waitforit
 
 import (
-	"crypto/tls"
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net"
 	"net/http"
-	"strconv"
+	"os"
+	"strings"
+	"sync"
 	"time"
 )
 
-// DialConfigs dial multiple connections at same time
-func DialConfigs(confs []Config, print func(a ...interface{})) error {
-	ch := make(chan error)
-	for _, config := range confs {
-		go func(conf Config) {
-			conn, err := BuildConn(&conf)
-			if err != nil {
-				ch <- fmt.Errorf("Invalid connection %#v: %v", conf, err)
+// Wait blocks until every config is available or has failed.
+// The configs that did not become available are reported in a *DialError.
+func Wait(ctx context.Context, confs []Config) error {
+	return DialConfigs(ctx, confs, nil)
+}
+
+// DialConfigs dial multiple connections at same time.
+// Invalid configs fail right away, otherwise it waits for every connection
+// and reports all the ones that did not become available in a *DialError.
+// A config only starts dialing once all the configs in its dependsOn are available.
+func DialConfigs(ctx context.Context, confs []Config, logger *slog.Logger) error {
+	logger = loggerOrDiscard(logger)
+	conns, err := buildConns(confs, "")
+	if err != nil {
+		return err
+	}
+
+	deps, err := buildDependencyGraph(confs)
+	if err != nil {
+		return err
+	}
+
+	return dialConns(ctx, conns, deps, logger)
+}
+
+// buildConns builds the connection of every config of the list (or group),
+// reporting all the invalid ones in a *DialError
+func buildConns(confs []Config, group string) ([]*Connection, error) {
+	conns := make([]*Connection, len(confs))
+	var failed []*TargetError
+	for i := range confs {
+		conf := confs[i]
+		conn, err := BuildConn(&conf)
+		if err != nil {
+			failed = append(failed, &TargetError{
+				Address: RedactAddress(conf.Address),
+				Config:  conf,
+				Index:   i,
+				Group:   group,
+				Err:     fmt.Errorf("Invalid connection: %v", err),
+			})
+			continue
+		}
+		conn.index = i
+		conn.group = group
+		conns[i] = conn
+	}
+
+	if len(failed) > 0 {
+		return nil, &DialError{Targets: failed, Total: len(confs)}
+	}
+
+	return conns, nil
+}
+
+// dialConns waits for every connection respecting their dependencies
+func dialConns(ctx context.Context, conns []*Connection, deps dependencyGraph, logger *slog.Logger) error {
+	errs := make([]error, len(conns))
+	done := make([]chan struct{}, len(conns))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Add(1)
+		go func(i int, conn *Connection) {
+			defer wg.Done()
+			defer close(done[i])
+
+			if err := waitDependencies(ctx, conn, deps[i], done, errs, logger); err != nil {
+				errs[i] = err
 				return
 			}
-
-			ch <- DialConn(conn, &conf, print)
-		}(config)
+			errs[i] = DialConn(ctx, conn, logger)
+		}(i, conn)
 	}
+	wg.Wait()
 
-	for i := 0; i < len(confs); i++ {
-		if err := <-ch; err != nil {
+	var failed []*TargetError
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		var te *TargetError
+		if !errors.As(err, &te) {
 			return err
 		}
+		failed = append(failed, te)
+	}
+
+	if len(failed) > 0 {
+		return &DialError{Targets: failed, Total: len(conns)}
 	}
 
 	return nil
 }
 
-// DialConn check if the connection is available
-func DialConn(conn *Connection, conf *Config, print func(a ...interface{})) error {
-	print("Waiting " + strconv.Itoa(conf.Timeout) + " seconds")
-	if err := pingHost(conn, conf, print); err != nil {
-		return err
-	}
+// waitDependencies blocks until the dependencies of the connection are done.
+// It returns a *TargetError naming the upstream config that blocked it
+// when any of them did not become available.
+func waitDependencies(ctx context.Context, conn *Connection, deps []int, done []chan struct{}, errs []error, logger *slog.Logger) error {
+	for k, j := range deps {
+		upstream := conn.Config.DependsOn[k]
+		blocked := func(err error) error {
+			return &TargetError{
+				Address:   conn.URL.Redacted(),
+				Config:    *conn.Config,
+				Index:     conn.index,
+				Group:     conn.group,
+				Err:       err,
+				BlockedBy: upstream,
+			}
+		}
 
-	if conn.URL.Scheme == "http" || conn.URL.Scheme == "https" {
-		return pingAddress(conn, conf, print)
+		logger.Debug("Waiting dependency", append(targetAttrs(conn), "dependency", upstream)...)
+		select {
+		case <-ctx.Done():
+			return blocked(ctx.Err())
+		case <-done[j]:
+		}
+
+		if errs[j] != nil {
+			logger.Warn("Blocked by dependency", append(targetAttrs(conn), "dependency", upstream)...)
+			return blocked(fmt.Errorf("Blocked by %s: dependency did not become available", upstream))
+		}
 	}
 
 	return nil
 }
 
-// pingAddress check if the full address is responding properly
-func pingAddress(conn *Connection, conf *Config, print func(a ...interface{})) error {
+// DialConn check if the connection is available (or, when the config
+// expects it down, unavailable) using the prober registered for its scheme.
+// The config timeout is a hard deadline, even for an attempt in progress.
+// On failure the returned error is a *TargetError. A nil logger discards the records.
+func DialConn(ctx context.Context, conn *Connection, logger *slog.Logger) error {
+	logger = loggerOrDiscard(logger)
+	conf := conn.Config
 	timeout := time.Duration(conf.Timeout) * time.Second
 	start := time.Now()
-	address := conn.URL.String()
-	print("Ping http address: " + address)
-	if conf.Status > 0 {
-		print("Expect HTTP status" + strconv.Itoa(conf.Status))
-	}
+	address := conn.URL.Redacted()
+	prober := LookupProber(conn.URL.Scheme)
+	retry := newBackoff(conf)
+	logger = logger.With(targetAttrs(conn)...)
+	logger.Debug("Waiting", "timeout", timeout)
 
-	client := &http.Client{}
-	if conf.Insecure {
-		client.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	fail := func(attempts int, err error) error {
+		return &TargetError{
+			Address:  address,
+			Config:   *conf,
+			Index:    conn.index,
+			Group:    conn.group,
+			Err:      err,
+			Attempts: attempts,
+			Elapsed:  time.Since(start),
 		}
 	}
 
-	req, err := http.NewRequest("GET", address, nil)
-	if err != nil {
-		return fmt.Errorf("Error creating request: %v", err)
+	var waitCtx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		waitCtx, cancel = context.WithCancel(ctx)
 	}
+	defer cancel()
 
-	for k, v := range conf.Headers {
-		print("Adding header " + k + ": " + v)
-		req.Header.Add(k, v)
-	}
+	// consecutive successes required before the target is considered ready
+	successes := 0
+	var upSince time.Time
+
+	hook := attemptHook(ctx)
 
-	for {
-		resp, err := client.Do(req)
-		if resp != nil {
-			print("Ping http address " + address + " " + resp.Status)
+	for attempts := 1; ; attempts++ {
+		logger.Debug("Ping", "attempt", attempts)
+		attemptStart := time.Now()
+		err := probeOnce(waitCtx, prober, conn)
+		latency := time.Since(attemptStart)
+		if ctx.Err() != nil {
+			return fail(attempts, ctx.Err())
 		}
 
-		if err == nil {
-			if conf.Status > 0 && conf.Status == resp.StatusCode {
-				return nil
-			} else if conf.Status == 0 && resp.StatusCode < http.StatusInternalServerError {
+		trace := func(err error, ready bool) {
+			hook(Attempt{
+				Name:    conf.Name,
+				Address: address,
+				Index:   conn.index,
+				Group:   conn.group,
+				Attempt: attempts,
+				Err:     err,
+				Ready:   ready,
+				Latency: latency,
+				Elapsed: time.Since(start),
+			})
+		}
+
+		// an attempt interrupted by the overall deadline is not an answer from the target
+		if err != nil && waitCtx.Err() != nil {
+			trace(err, false)
+			return fail(attempts, err)
+		}
+
+		state := "Up"
+		if err != nil {
+			state = "Down"
+		}
+		err = conf.expected(err)
+		reached := err == nil
+
+		interval := conf.retryInterval()
+		if reached {
+			successes++
+			if successes == 1 {
+				upSince = time.Now()
+			}
+
+			if conf.isStable(successes, time.Since(upSince)) {
+				logger.Info(state, "attempt", attempts, "latency", latency, "elapsed", time.Since(start))
+				trace(nil, true)
 				return nil
 			}
+
+			trace(nil, false)
+			logger.Debug(state, "attempt", attempts, "latency", latency, "successes", successes)
+			err = fmt.Errorf("Not stable after %d consecutive successes in %v", successes, time.Since(upSince).Round(time.Millisecond))
+		} else {
+			successes = 0
+			trace(err, false)
+			logger.Info(state, "attempt", attempts, "latency", latency, "error", err)
+			interval = retry.next()
 		}
 
-		if time.Since(start) > timeout {
-			return errors.New(resp.Status)
+		if waitCtx.Err() != nil || time.Since(start) >= timeout {
+			return fail(attempts, err)
 		}
 
-		time.Sleep(time.Duration(conf.Retry) * time.Millisecond)
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return fail(attempts, ctx.Err())
+			}
+			return fail(attempts, err)
+		case <-time.After(interval):
+		}
 	}
 }
 
-// pingHost check if the host (hostname:port) is responding properly
-func pingHost(conn *Connection, conf *Config, print func(a ...interface{})) error {
-	timeout := time.Duration(conf.Timeout) * time.Second
-	start := time.Now()
-	address := conn.URL.Host
-	print("Ping host: " + address)
+// loggerOrDiscard returns the logger or, when it is nil, one discarding every record
+func loggerOrDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return logger
+}
 
-	for {
-		_, err := net.DialTimeout(conn.NetworkType, address, time.Second)
-		print("Ping host: " + address)
+// targetAttrs returns the attributes identifying the target on log records
+func targetAttrs(conn *Connection) []interface{} {
+	attrs := []interface{}{"address", conn.URL.Redacted()}
+	if conn.Config.Name != "" {
+		attrs = append([]interface{}{"target", conn.Config.Name}, attrs...)
+	}
+	return attrs
+}
 
-		if err == nil {
-			print("Up: " + address)
-			return nil
+// defaultRetryInterval is the interval between the attempts
+// to reach a target when the config does not set the retry
+const defaultRetryInterval = time.Second
+
+// retryInterval returns the interval between the attempts to reach the target
+func (c *Config) retryInterval() time.Duration {
+	if c.Retry > 0 {
+		return time.Duration(c.Retry) * time.Millisecond
+	}
+
+	return defaultRetryInterval
+}
+
+// defaultAttemptTimeout limits how long a single attempt
+// to reach a target can take when the config does not set it
+const defaultAttemptTimeout = 5 * time.Second
+
+// attemptTimeout returns how long a single attempt to reach the target can take
+func (c *Config) attemptTimeout() time.Duration {
+	if c.AttemptTimeout > 0 {
+		return time.Duration(c.AttemptTimeout) * time.Millisecond
+	}
+
+	return defaultAttemptTimeout
+}
+
+// dialTarget connects to the target bounding the connection by the
+// attempt deadline. The connection is also interrupted once ctx is done,
+// so any pending read or write returns right away.
+func dialTarget(ctx context.Context, conn *Connection) (net.Conn, error) {
+	var d net.Dialer
+	c, err := d.DialContext(ctx, conn.NetworkType, conn.dialAddress())
+	if err != nil {
+		return nil, err
+	}
+
+	if dl, ok := ctx.Deadline(); ok {
+		if err := c.SetDeadline(dl); err != nil {
+			c.Close() // nolint
+			return nil, err
 		}
+	}
 
-		print("Down: " + address)
-		print(err)
-		if time.Since(start) > timeout {
-			return err
+	context.AfterFunc(ctx, func() {
+		c.SetDeadline(time.Now()) // nolint
+	})
+
+	return c, nil
+}
+
+// expected converts the result of an attempt into the error of not
+// being in the expected state, which is nil when the state was reached
+func (c *Config) expected(err error) error {
+	if c.Expect != ExpectDown {
+		return err
+	}
+
+	if err == nil {
+		return errors.New("Still up")
+	}
+
+	return nil
+}
+
+// probeOnce runs a single attempt bounded by the attempt timeout,
+// so anything left behind by the prober is released once it returns
+func probeOnce(ctx context.Context, prober Prober, conn *Connection) error {
+	ctx, cancel := context.WithTimeout(ctx, conn.Config.attemptTimeout())
+	defer cancel()
+
+	return prober.Probe(ctx, conn)
+}
+
+// pingAddress check if the full address is responding properly
+func pingAddress(ctx context.Context, conn *Connection) error {
+	conf := conn.Config
+
+	tc, err := tlsConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tc
+
+	if conn.NetworkType == "unix" {
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, conn.NetworkType, conn.SocketPath)
+		}
+	}
+
+	client := &http.Client{Transport: transport}
+	defer client.CloseIdleConnections()
+
+	body, err := requestBody(conf)
+	if err != nil {
+		return err
+	}
+
+	method := "GET"
+	if conf.Method != "" {
+		method = strings.ToUpper(conf.Method)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, conn.requestURL(), body)
+	if err != nil {
+		return fmt.Errorf("Error creating request: %v", err)
+	}
+
+	for k, v := range conf.Headers {
+		req.Header.Add(k, v)
+	}
+
+	if conf.ContentType != "" {
+		req.Header.Set("Content-Type", conf.ContentType)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint
+
+	ok, err := conf.Status.Match(resp.StatusCode)
+	if err != nil {
+		return err
+	}
+
+	if !ok && !conf.Status.isDefault() {
+		return fmt.Errorf("Unexpected HTTP status %q, expected %s", resp.Status, conf.Status)
+	} else if !ok {
+		return fmt.Errorf("Unexpected HTTP status %q", resp.Status)
+	}
+
+	if !conf.hasBodyAssertions() {
+		return nil
+	}
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return fmt.Errorf("Error reading response body: %v", err)
+	}
+
+	return checkBody(conf, respBody)
+}
+
+// requestBody returns the body sent in the http(s) ping request.
+// It is loaded on every attempt so each request has a fresh body.
+func requestBody(conf *Config) (io.Reader, error) {
+	if conf.BodyFile != "" {
+		b, err := os.ReadFile(conf.BodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading body file: %v", err)
 		}
+		return bytes.NewReader(b), nil
+	}
 
-		time.Sleep(time.Duration(conf.Retry) * time.Millisecond)
+	if conf.Body != "" {
+		return strings.NewReader(conf.Body), nil
 	}
+
+	return nil, nil
+}
+
+// pingHost check if the host (hostname:port or unix socket) is responding properly
+func pingHost(ctx context.Context, conn *Connection) error {
+	var d net.Dialer
+	c, err := d.DialContext(ctx, conn.NetworkType, conn.dialAddress())
+	if err != nil {
+		return err
+	}
+
+	return c.Close()
 }
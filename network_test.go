@@ -1,11 +1,19 @@
-package main_test
+package waitforit_test
 
 import (
+	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -71,12 +79,12 @@ func (s *Server) Close() (err error) {
 }
 
 func TestDialConn(t *testing.T) {
-	print := func(a ...interface{}) {}
+	logger := slog.New(slog.DiscardHandler)
 
 	testCases := []struct {
 		title         string
 		cfg           *Config
-		status        int
+		status        StatusCodes
 		allowStart    bool
 		openConnAfter int
 		finishOk      bool
@@ -86,7 +94,7 @@ func TestDialConn(t *testing.T) {
 		{
 			title:         "Should successfully check connection that is already available.",
 			cfg:           &Config{Address: "localhost:8080"},
-			status:        0,
+			status:        "",
 			allowStart:    true,
 			openConnAfter: 0,
 			finishOk:      true,
@@ -95,7 +103,7 @@ func TestDialConn(t *testing.T) {
 		{
 			title:         "Should successfully check connection that open before reach the timeout.",
 			cfg:           &Config{Address: "localhost:8080"},
-			status:        0,
+			status:        "",
 			allowStart:    true,
 			openConnAfter: 2,
 			finishOk:      true,
@@ -104,7 +112,7 @@ func TestDialConn(t *testing.T) {
 		{
 			title:         "Should successfully check a HTTP connection that is already available.",
 			cfg:           &Config{Address: "http://localhost:8080"},
-			status:        0,
+			status:        "",
 			allowStart:    true,
 			openConnAfter: 0,
 			finishOk:      true,
@@ -113,7 +121,7 @@ func TestDialConn(t *testing.T) {
 		{
 			title:         "Should successfully check a HTTPS connection that is already available.",
 			cfg:           &Config{Address: "https://localhost:8443", Insecure: true},
-			status:        0,
+			status:        "",
 			allowStart:    true,
 			openConnAfter: 0,
 			finishOk:      true,
@@ -122,7 +130,7 @@ func TestDialConn(t *testing.T) {
 		{
 			title:         "Should successfully check a HTTP connection that open before reach the timeout.",
 			cfg:           &Config{Address: "http://localhost:8080"},
-			status:        0,
+			status:        "",
 			allowStart:    true,
 			openConnAfter: 2,
 			finishOk:      true,
@@ -131,7 +139,7 @@ func TestDialConn(t *testing.T) {
 		{
 			title:         "Should successfully check a HTTP connection that returns 404 status code.",
 			cfg:           &Config{Address: "http://localhost:8080"},
-			status:        0,
+			status:        "",
 			allowStart:    true,
 			openConnAfter: 0,
 			finishOk:      true,
@@ -142,7 +150,7 @@ func TestDialConn(t *testing.T) {
 		{
 			title:         "Should fail checking a HTTP connection that returns 500 status code.",
 			cfg:           &Config{Address: "http://localhost:8080"},
-			status:        0,
+			status:        "",
 			allowStart:    true,
 			openConnAfter: 0,
 			finishOk:      false,
@@ -153,7 +161,7 @@ func TestDialConn(t *testing.T) {
 		{
 			title:         "Should successfully check a HTTP connection that returns 200 status code before reach the timeout.",
 			cfg:           &Config{Address: "http://localhost:8080"},
-			status:        200,
+			status:        "200",
 			allowStart:    true,
 			openConnAfter: 2,
 			finishOk:      true,
@@ -164,7 +172,7 @@ func TestDialConn(t *testing.T) {
 		{
 			title:         "Should fail checking a HTTP connection that returns not expected status code.",
 			cfg:           &Config{Address: "http://localhost:8080"},
-			status:        200,
+			status:        "200",
 			allowStart:    true,
 			openConnAfter: 0,
 			finishOk:      false,
@@ -175,7 +183,7 @@ func TestDialConn(t *testing.T) {
 		{
 			title:         "Should not crash on checking a HTTP connection with not authorized basic auth.",
 			cfg:           &Config{Address: "http://localhost:8080"},
-			status:        0,
+			status:        "",
 			allowStart:    true,
 			openConnAfter: 0,
 			finishOk:      true,
@@ -184,7 +192,7 @@ func TestDialConn(t *testing.T) {
 		{
 			title:         "Should support passing basic auth header on checking a HTTP connection.",
 			cfg:           &Config{Address: "http://localhost:8080"},
-			status:        200,
+			status:        "200",
 			allowStart:    true,
 			openConnAfter: 0,
 			finishOk:      true,
@@ -199,7 +207,16 @@ func TestDialConn(t *testing.T) {
 		t.Run(v.title, func(t *testing.T) {
 			var err error
 
-			conn, err := BuildConn(&Config{Address: v.cfg.Address})
+			conf := &Config{
+				Address:  v.cfg.Address,
+				Timeout:  defaultTimeout,
+				Retry:    defaultRetry,
+				Status:   v.status,
+				Headers:  v.headers,
+				Insecure: v.cfg.Insecure,
+			}
+
+			conn, err := BuildConn(conf)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -219,15 +236,7 @@ func TestDialConn(t *testing.T) {
 				}()
 			}
 
-			conf := &Config{
-				Timeout:  defaultTimeout,
-				Retry:    defaultRetry,
-				Status:   v.status,
-				Headers:  v.headers,
-				Insecure: v.cfg.Insecure,
-			}
-
-			err = DialConn(conn, conf, print)
+			err = DialConn(context.Background(), conn, logger)
 			if err != nil && v.finishOk {
 				t.Errorf("Expected to connect successfully %s. But got error %v.", v.cfg.Address, err)
 				return
@@ -241,7 +250,7 @@ func TestDialConn(t *testing.T) {
 }
 
 func TestDialConfigs(t *testing.T) { // nolint gocyclo
-	print := func(a ...interface{}) {}
+	logger := slog.New(slog.DiscardHandler)
 
 	type testItem struct {
 		conf          Config
@@ -357,7 +366,7 @@ func TestDialConfigs(t *testing.T) { // nolint gocyclo
 				}
 			}
 
-			err := DialConfigs(confs, print)
+			err := DialConfigs(context.Background(), confs, logger)
 			if err != nil && finishAllOk {
 				t.Errorf("Expected to connect successfully %#v. But got error %v.", confs, err)
 				return
@@ -370,6 +379,275 @@ func TestDialConfigs(t *testing.T) { // nolint gocyclo
 	}
 }
 
+func TestDialConnCancel(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	conn, err := BuildConn(&Config{Address: "localhost:8089", Timeout: 30, Retry: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(500*time.Millisecond, cancel)
+
+	start := time.Now()
+	err = DialConn(ctx, conn, logger)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected wait to be cancelled, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected wait to abort right after the cancellation, took %v", elapsed)
+	}
+}
+
+func TestDialConfigsInvalidConfig(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	confs := []Config{
+		{Timeout: 30},
+		{Address: "localhost:8089", Timeout: 30, Retry: 500},
+	}
+
+	start := time.Now()
+	if err := DialConfigs(context.Background(), confs, logger); err == nil {
+		t.Fatal("Expected invalid connection to fail")
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected invalid configs to fail before waiting, took %v", elapsed)
+	}
+}
+
+func TestDialConfigsInvalidConfigRedacted(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	confs := []Config{
+		{Address: "postgres://user:secret@db/app", Status: "999", Timeout: 30},
+	}
+
+	err := DialConfigs(context.Background(), confs, logger)
+
+	var de *DialError
+	if !errors.As(err, &de) {
+		t.Fatalf("Expected a *DialError, got %#v", err)
+	}
+
+	assertEqual(t, "address", de.Targets[0].Address, "postgres://user:xxxxx@db/app")
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("Expected the password to be redacted, got %q", err.Error())
+	}
+}
+
+func TestDialConfigsReportAllFailures(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	confs := []Config{
+		{Address: "localhost:8087", Timeout: 1, Retry: 200},
+		{Address: "localhost:8088", Timeout: 1, Retry: 200},
+		{Address: "localhost:8089", Timeout: 1, Retry: 200},
+	}
+
+	l, err := net.Listen("tcp", "localhost:8088")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close() // nolint
+
+	err = DialConfigs(context.Background(), confs, logger)
+
+	var de *DialError
+	if !errors.As(err, &de) {
+		t.Fatalf("Expected a *DialError, got %#v", err)
+	}
+
+	assertEqual(t, "total", de.Total, 3)
+	assertEqual(t, "failed targets", len(de.Targets), 2)
+	for i, address := range []string{"tcp://localhost:8087", "tcp://localhost:8089"} {
+		assertEqual(t, "address", de.Targets[i].Address, address)
+		if de.Targets[i].Attempts < 2 {
+			t.Errorf("Expected %s to be retried, got %d attempts", address, de.Targets[i].Attempts)
+		}
+	}
+
+	if !strings.Contains(err.Error(), "2 of 3 targets are not available") {
+		t.Errorf("Unexpected summary %q", err.Error())
+	}
+}
+
+func TestDialConfigsDependencies(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	var dbReady, apiHitEarly atomic.Bool
+	var dbHits atomic.Int32
+	db := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if dbHits.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		dbReady.Store(true)
+	}))
+	defer db.Close()
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !dbReady.Load() {
+			apiHitEarly.Store(true)
+		}
+	}))
+	defer api.Close()
+
+	confs := []Config{
+		{Name: "api", DependsOn: []string{"migration"}, Address: api.URL, Timeout: 2, Retry: 100},
+		{Name: "migration", DependsOn: []string{"db"}, Address: api.URL, Timeout: 2, Retry: 100},
+		{Name: "db", Address: db.URL, Timeout: 2, Retry: 100},
+	}
+
+	if err := DialConfigs(context.Background(), confs, logger); err != nil {
+		t.Fatalf("Expected to connect successfully. But got error %v.", err)
+	}
+
+	if apiHitEarly.Load() {
+		t.Error("Expected dependent configs to wait for their dependencies")
+	}
+}
+
+func TestDialConfigsBlockedByDependency(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	confs := []Config{
+		{Name: "db", Address: "localhost:8087", Timeout: 1, Retry: 200},
+		{Name: "api", DependsOn: []string{"db"}, Address: "localhost:8088", Timeout: 1, Retry: 200},
+	}
+
+	err := DialConfigs(context.Background(), confs, logger)
+
+	var de *DialError
+	if !errors.As(err, &de) {
+		t.Fatalf("Expected a *DialError, got %#v", err)
+	}
+
+	assertEqual(t, "failed targets", len(de.Targets), 2)
+	assertEqual(t, "blocked by", de.Targets[1].BlockedBy, "db")
+	assertEqual(t, "attempts", de.Targets[1].Attempts, 0)
+
+	if !strings.Contains(err.Error(), "Blocked by db") {
+		t.Errorf("Unexpected summary %q", err.Error())
+	}
+}
+
+func TestDialConfigsInvalidDependencies(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	testCases := []struct {
+		title string
+		confs []Config
+		err   string
+	}{
+		{
+			title: "Should fail with an unknown dependency",
+			confs: []Config{
+				{Name: "api", DependsOn: []string{"db"}, Address: "localhost:8088"},
+			},
+			err: `Config "api" depends on unknown config "db"`,
+		},
+		{
+			title: "Should fail with duplicated names",
+			confs: []Config{
+				{Name: "db", Address: "localhost:8087"},
+				{Name: "db", Address: "localhost:8088"},
+			},
+			err: `Duplicated config name "db"`,
+		},
+		{
+			title: "Should fail with a dependency cycle",
+			confs: []Config{
+				{Name: "db", Address: "localhost:8087"},
+				{Name: "migration", DependsOn: []string{"db", "api"}, Address: "localhost:8088"},
+				{Name: "api", DependsOn: []string{"migration"}, Address: "localhost:8089"},
+			},
+			err: "Dependency cycle: migration -> api -> migration",
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			err := DialConfigs(context.Background(), v.confs, logger)
+			if err == nil {
+				t.Fatal("Expected invalid dependencies to fail")
+			}
+			assertEqual(t, "error", err.Error(), v.err)
+		})
+	}
+}
+
+func TestDialConnUnix(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	socketPath := filepath.Join(t.TempDir(), "waitforit.sock")
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &httptest.Server{
+		Listener: l,
+		Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/healthz" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte("OK"))
+		})},
+	}
+	s.Start()
+	defer s.Close()
+
+	testCases := []struct {
+		title    string
+		address  string
+		finishOk bool
+	}{
+		{
+			title:    "Should successfully check a unix socket",
+			address:  "unix://" + socketPath,
+			finishOk: true,
+		},
+		{
+			title:    "Should successfully check a HTTP endpoint over a unix socket",
+			address:  "http+unix://" + socketPath + ":/healthz",
+			finishOk: true,
+		},
+		{
+			title:    "Should fail checking a HTTP endpoint over a unix socket with unexpected status",
+			address:  "http+unix://" + socketPath + ":/missing",
+			finishOk: false,
+		},
+		{
+			title:    "Should fail checking a unix socket that does not exist",
+			address:  "unix://" + socketPath + ".missing",
+			finishOk: false,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			conn, err := BuildConn(&Config{Address: v.address, Status: "200", Timeout: 1, Retry: 200})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = DialConn(context.Background(), conn, logger)
+			if err != nil && v.finishOk {
+				t.Errorf("Expected to connect successfully %s. But got error %v.", v.address, err)
+			}
+
+			if err == nil && !v.finishOk {
+				t.Errorf("Expected to not connect successfully %s.", v.address)
+			}
+		})
+	}
+}
+
 func basicAuthHandler(w http.ResponseWriter, r *http.Request) {
 	authorizationArray := r.Header["Authorization"]
 
@@ -408,3 +686,371 @@ func unauthorized(w http.ResponseWriter) {
 	w.Header().Set("WWW-Authenticate", "Basic realm=\"user\"")
 	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 }
+
+func TestDialConnRequest(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	var requests int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		body, _ := io.ReadAll(r.Body)
+
+		// fail the first request to ensure the body is sent again on retries
+		if n == 1 || r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" || string(body) != `{"query":"{ health }"}` {
+			http.Error(w, "", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("OK")) // nolint
+	}))
+	defer s.Close()
+
+	bodyFile := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(bodyFile, []byte(`{"query":"{ health }"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		title    string
+		cfg      Config
+		finishOk bool
+	}{
+		{
+			title:    "Should send the configured method, body and content type",
+			cfg:      Config{Method: "post", Body: `{"query":"{ health }"}`, ContentType: "application/json"},
+			finishOk: true,
+		},
+		{
+			title:    "Should send the body loaded from a file",
+			cfg:      Config{Method: "POST", BodyFile: bodyFile, ContentType: "application/json"},
+			finishOk: true,
+		},
+		{
+			title:    "Should fail when the request does not match what the server expects",
+			cfg:      Config{Method: "PUT", Body: `{"query":"{ health }"}`, ContentType: "application/json"},
+			finishOk: false,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+			cfg := v.cfg
+			cfg.Address = s.URL
+			cfg.Status = "200"
+			cfg.Timeout = 1
+			cfg.Retry = 100
+
+			conn, err := BuildConn(&cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = DialConn(context.Background(), conn, logger)
+			if err != nil && v.finishOk {
+				t.Errorf("Expected to connect successfully %s. But got error %v.", cfg.Address, err)
+			}
+
+			if err == nil && !v.finishOk {
+				t.Errorf("Expected to not connect successfully %s.", cfg.Address)
+			}
+		})
+	}
+}
+
+func TestDialConnTimeouts(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	var requests int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first request hangs until the client gives up on it
+		if atomic.AddInt32(&requests, 1) == 1 || r.URL.Path == "/hang" {
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte("OK")) // nolint
+	}))
+	defer s.Close()
+
+	t.Run("Should retry when a single attempt exceeds the attempt timeout", func(t *testing.T) {
+		conn, err := BuildConn(&Config{Address: s.URL, Timeout: 5, Retry: 50, AttemptTimeout: 200})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		if err := DialConn(context.Background(), conn, logger); err != nil {
+			t.Fatalf("Expected to connect successfully %s. But got error %v.", s.URL, err)
+		}
+
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("Expected the hung attempt to be abandoned, took %v", elapsed)
+		}
+	})
+
+	t.Run("Should enforce the overall timeout in the middle of an attempt", func(t *testing.T) {
+		conn, err := BuildConn(&Config{Address: s.URL + "/hang", Timeout: 1, Retry: 50, AttemptTimeout: 60000})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		err = DialConn(context.Background(), conn, logger)
+
+		var te *TargetError
+		if !errors.As(err, &te) {
+			t.Fatalf("Expected a *TargetError, got %#v", err)
+		}
+
+		assertEqual(t, "attempts", te.Attempts, 1)
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("Expected the wait to stop at the timeout, took %v", elapsed)
+		}
+	})
+}
+
+func TestBuildConnExpectDownInvalidFiles(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	testCases := []struct {
+		title string
+		cfg   Config
+		err   string
+	}{
+		{
+			title: "Should fail with a missing CA file",
+			cfg:   Config{CAFile: "/nonexistent.pem"},
+			err:   "Error reading CA file",
+		},
+		{
+			title: "Should fail with a missing client certificate",
+			cfg:   Config{CertFile: "/nonexistent.pem", KeyFile: "/nonexistent.key"},
+			err:   "Error loading client certificate",
+		},
+		{
+			title: "Should fail with a missing body file",
+			cfg:   Config{BodyFile: "/nonexistent"},
+			err:   "Error reading body file",
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			cfg := v.cfg
+			cfg.Address = s.URL
+			cfg.Expect = ExpectDown
+			_, err := BuildConn(&cfg)
+			if err == nil || !strings.Contains(err.Error(), v.err) {
+				t.Errorf("Expected error %q while %s is up, got %v", v.err, s.URL, err)
+			}
+		})
+	}
+}
+
+func TestDialConnExpectDown(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close() // nolint
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			c.Close() // nolint
+		}
+	}()
+
+	closing, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(500*time.Millisecond, func() { closing.Close() }) // nolint
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	testCases := []struct {
+		title    string
+		address  string
+		finishOk bool
+	}{
+		{
+			title:    "Should fail while the port keeps accepting connections",
+			address:  "tcp://" + l.Addr().String(),
+			finishOk: false,
+		},
+		{
+			title:    "Should succeed once the port is closed",
+			address:  "tcp://" + closing.Addr().String(),
+			finishOk: true,
+		},
+		{
+			title:    "Should succeed when the HTTP endpoint fails",
+			address:  failing.URL,
+			finishOk: true,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			conn, err := BuildConn(&Config{Address: v.address, Expect: ExpectDown, Timeout: 2, Retry: 100})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = DialConn(context.Background(), conn, logger)
+			if err != nil && v.finishOk {
+				t.Errorf("Expected %s to go down. But got error %v.", v.address, err)
+			}
+
+			if err == nil && !v.finishOk {
+				t.Errorf("Expected %s to not go down.", v.address)
+			}
+		})
+	}
+}
+
+func TestDialConnAttemptHook(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	var hits atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer s.Close()
+
+	var attempts []Attempt
+	ctx := WithAttemptHook(context.Background(), func(a Attempt) {
+		attempts = append(attempts, a)
+	})
+
+	conn, err := BuildConn(&Config{Name: "api", Address: s.URL, Timeout: 2, Retry: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DialConn(ctx, conn, logger); err != nil {
+		t.Fatalf("Expected to connect successfully %s. But got error %v.", s.URL, err)
+	}
+
+	assertEqual(t, "attempts", len(attempts), 3)
+	for i, a := range attempts {
+		assertEqual(t, "name", a.Name, "api")
+		assertEqual(t, "attempt", a.Attempt, i+1)
+		assertEqual(t, "ready", a.Ready, i == 2)
+		assertEqual(t, "failed", a.Err != nil, i < 2)
+	}
+}
+
+func TestDialConnLogAttributes(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	var b strings.Builder
+	logger := slog.New(slog.NewJSONHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	conn, err := BuildConn(&Config{Name: "api", Address: s.URL, Timeout: 2, Retry: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DialConn(context.Background(), conn, logger); err != nil {
+		t.Fatalf("Expected to connect successfully %s. But got error %v.", s.URL, err)
+	}
+
+	var up map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, "target", record["target"], "api")
+		assertEqual(t, "address", record["address"], s.URL)
+		if record["msg"] == "Up" {
+			up = record
+		}
+	}
+
+	if up == nil {
+		t.Fatalf("Expected an Up record, got %s", b.String())
+	}
+	assertEqual(t, "level", up["level"], "INFO")
+	assertEqual(t, "attempt", up["attempt"], float64(1))
+	if _, ok := up["latency"]; !ok {
+		t.Error("Expected the Up record to have the latency")
+	}
+}
+
+func TestDialNilLogger(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close() // nolint
+
+	confs := []Config{{Address: "tcp://" + l.Addr().String(), Timeout: 1}}
+	fc := FileConfig{Configs: confs, Groups: []Group{{Name: "cluster", Configs: confs}}}
+
+	conn, err := BuildConn(&confs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DialConn(context.Background(), conn, nil); err != nil {
+		t.Errorf("DialConn: %v", err)
+	}
+	if err := DialConfigs(context.Background(), confs, nil); err != nil {
+		t.Errorf("DialConfigs: %v", err)
+	}
+	if err := DialGroup(context.Background(), fc.Groups[0], nil); err != nil {
+		t.Errorf("DialGroup: %v", err)
+	}
+	if err := DialFileConfig(context.Background(), fc, nil); err != nil {
+		t.Errorf("DialFileConfig: %v", err)
+	}
+
+	m, err := NewMonitor(fc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	m.Run(ctx)
+	if !m.Ready() {
+		t.Error("Expected the monitor to be ready")
+	}
+}
+
+func TestDialConnDefaultRetry(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	var attempts atomic.Int32
+	ctx := WithAttemptHook(context.Background(), func(Attempt) {
+		attempts.Add(1)
+	})
+
+	for _, cfg := range []Config{
+		{Address: "tcp://localhost:8087", Timeout: 1},
+		{Address: "tcp://localhost:8087", Timeout: 1, Expect: ExpectDown, SuccessThreshold: 5},
+	} {
+		attempts.Store(0)
+		conn, err := BuildConn(&cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		DialConn(ctx, conn, logger) // nolint
+		if n := attempts.Load(); n > 2 {
+			t.Errorf("Expected the attempts to wait the default retry, got %d attempts", n)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package waitforit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterProber("postgres", ProberFunc(pingPostgres))
+	RegisterProber("postgresql", ProberFunc(pingPostgres))
+}
+
+const (
+	pgProtocolVersion = 196608 // 3.0
+	pgSSLRequestCode  = 80877103
+
+	pgAuthOK           = 0
+	pgAuthCleartext    = 3
+	pgAuthMD5          = 5
+	pgAuthSASL         = 10
+	pgAuthSASLContinue = 11
+	pgAuthSASLFinal    = 12
+)
+
+// pingPostgres check if the postgres server accepts connections by completing
+// the startup handshake (including authentication) and, when a query is
+// configured, running it.
+func pingPostgres(ctx context.Context, conn *Connection) error {
+	c, err := dialTarget(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer c.Close() // nolint
+
+	user := conn.URL.User.Username()
+	if user == "" {
+		user = "postgres"
+	}
+	password, _ := conn.URL.User.Password()
+	database := strings.TrimPrefix(conn.URL.Path, "/")
+	if database == "" {
+		database = user
+	}
+
+	pg := &pgConn{r: bufio.NewReader(c), w: c}
+	if err := pg.startup(user, password, database); err != nil {
+		return err
+	}
+
+	if conn.Config.Query != "" {
+		if err := pg.query(conn.Config.Query); err != nil {
+			return err
+		}
+	}
+
+	return pg.send('X', nil)
+}
+
+// pgSSLRequest asks the server to continue the connection over tls,
+// postgres only starts the tls handshake after accepting it
+func pgSSLRequest(rw io.ReadWriter) error {
+	msg := binary.BigEndian.AppendUint32(nil, 8)
+	msg = binary.BigEndian.AppendUint32(msg, pgSSLRequestCode)
+	if _, err := rw.Write(msg); err != nil {
+		return err
+	}
+
+	var answer [1]byte
+	if _, err := io.ReadFull(rw, answer[:]); err != nil {
+		return err
+	}
+
+	switch answer[0] {
+	case 'S':
+		return nil
+	case 'N':
+		return errors.New("postgres: server does not accept tls connections")
+	}
+
+	return fmt.Errorf("postgres: unexpected answer %q to the tls request", answer[0])
+}
+
+type pgConn struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+// pgError is the error reported by the server in an ErrorResponse message
+type pgError struct {
+	Code    string
+	Message string
+}
+
+func (e *pgError) Error() string {
+	return fmt.Sprintf("postgres: %s (SQLSTATE %s)", e.Message, e.Code)
+}
+
+func (pg *pgConn) startup(user, password, database string) error {
+	var b []byte
+	b = binary.BigEndian.AppendUint32(b, pgProtocolVersion)
+	b = appendCString(b, "user")
+	b = appendCString(b, user)
+	b = appendCString(b, "database")
+	b = appendCString(b, database)
+	b = append(b, 0)
+
+	// the startup message is the only one without a type byte
+	msg := binary.BigEndian.AppendUint32(nil, uint32(len(b)+4))
+	if _, err := pg.w.Write(append(msg, b...)); err != nil {
+		return err
+	}
+
+	var scram *scramClient
+	for {
+		t, body, err := pg.receive()
+		if err != nil {
+			return err
+		}
+
+		switch t {
+		case 'R':
+			if len(body) < 4 {
+				return errors.New("postgres: invalid authentication message")
+			}
+
+			switch code := binary.BigEndian.Uint32(body); code {
+			case pgAuthOK:
+			case pgAuthCleartext:
+				err = pg.send('p', appendCString(nil, password))
+			case pgAuthMD5:
+				if len(body) < 8 {
+					return errors.New("postgres: invalid md5 salt")
+				}
+				err = pg.send('p', appendCString(nil, pgMD5Password(user, password, body[4:8])))
+			case pgAuthSASL:
+				if !containsCString(body[4:], "SCRAM-SHA-256") {
+					return errors.New("postgres: server does not support SCRAM-SHA-256")
+				}
+				if scram, err = newScramClient(password); err != nil {
+					return err
+				}
+				first := scram.clientFirst()
+				b := appendCString(nil, "SCRAM-SHA-256")
+				b = binary.BigEndian.AppendUint32(b, uint32(len(first)))
+				err = pg.send('p', append(b, first...))
+			case pgAuthSASLContinue:
+				if scram == nil {
+					return errors.New("postgres: unexpected SASL continue message")
+				}
+				var final string
+				if final, err = scram.clientFinal(string(body[4:])); err == nil {
+					err = pg.send('p', []byte(final))
+				}
+			case pgAuthSASLFinal:
+				if scram == nil {
+					return errors.New("postgres: unexpected SASL final message")
+				}
+				err = scram.verifyServerFinal(string(body[4:]))
+			default:
+				return fmt.Errorf("postgres: unsupported authentication method %d", code)
+			}
+
+			if err != nil {
+				return err
+			}
+		case 'E':
+			return parsePgError(body)
+		case 'Z':
+			return nil
+		}
+	}
+}
+
+func (pg *pgConn) query(q string) error {
+	if err := pg.send('Q', appendCString(nil, q)); err != nil {
+		return err
+	}
+
+	var qerr error
+	for {
+		t, body, err := pg.receive()
+		if err != nil {
+			return err
+		}
+
+		switch t {
+		case 'E':
+			qerr = parsePgError(body)
+		case 'Z':
+			return qerr
+		}
+	}
+}
+
+func (pg *pgConn) send(t byte, body []byte) error {
+	msg := []byte{t}
+	msg = binary.BigEndian.AppendUint32(msg, uint32(len(body)+4))
+	_, err := pg.w.Write(append(msg, body...))
+	return err
+}
+
+func (pg *pgConn) receive() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(pg.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	n := binary.BigEndian.Uint32(header[1:])
+	if n < 4 || n > 1<<24 {
+		return 0, nil, fmt.Errorf("postgres: invalid message length %d", n)
+	}
+
+	body := make([]byte, n-4)
+	if _, err := io.ReadFull(pg.r, body); err != nil {
+		return 0, nil, err
+	}
+
+	return header[0], body, nil
+}
+
+func parsePgError(body []byte) error {
+	e := &pgError{}
+	for len(body) > 1 {
+		field := body[0]
+		end := bytes.IndexByte(body[1:], 0)
+		if end < 0 {
+			break
+		}
+
+		value := string(body[1 : end+1])
+		switch field {
+		case 'C':
+			e.Code = value
+		case 'M':
+			e.Message = value
+		}
+		body = body[end+2:]
+	}
+
+	return e
+}
+
+func pgMD5Password(user, password string, salt []byte) string {
+	inner := md5.Sum([]byte(password + user))
+	outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+	return "md5" + hex.EncodeToString(outer[:])
+}
+
+func appendCString(b []byte, s string) []byte {
+	return append(append(b, s...), 0)
+}
+
+func containsCString(b []byte, s string) bool {
+	for _, v := range strings.Split(string(b), "\x00") {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// scramClient implements the client side of SCRAM-SHA-256 (RFC 7677)
+type scramClient struct {
+	password    string
+	nonce       string
+	authMessage string
+	saltedPass  []byte
+}
+
+func newScramClient(password string) (*scramClient, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	return &scramClient{password: password, nonce: base64.RawStdEncoding.EncodeToString(b)}, nil
+}
+
+func (s *scramClient) clientFirstBare() string {
+	// postgres ignores the user name sent in the SCRAM exchange
+	return "n=,r=" + s.nonce
+}
+
+func (s *scramClient) clientFirst() string {
+	return "n,," + s.clientFirstBare()
+}
+
+func (s *scramClient) clientFinal(serverFirst string) (string, error) {
+	attrs := parseScramAttrs(serverFirst)
+
+	nonce := attrs["r"]
+	if !strings.HasPrefix(nonce, s.nonce) {
+		return "", errors.New("scram: invalid server nonce")
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(attrs["s"])
+	if err != nil {
+		return "", fmt.Errorf("scram: invalid salt: %v", err)
+	}
+
+	iterations, err := strconv.Atoi(attrs["i"])
+	if err != nil || iterations < 1 {
+		return "", fmt.Errorf("scram: invalid iteration count %q", attrs["i"])
+	}
+
+	s.saltedPass, err = pbkdf2.Key(sha256.New, s.password, salt, iterations, sha256.Size)
+	if err != nil {
+		return "", err
+	}
+
+	withoutProof := "c=biws,r=" + nonce
+	s.authMessage = s.clientFirstBare() + "," + serverFirst + "," + withoutProof
+
+	clientKey := hmacSHA256(s.saltedPass, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	signature := hmacSHA256(storedKey[:], s.authMessage)
+
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ signature[i]
+	}
+
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+func (s *scramClient) verifyServerFinal(serverFinal string) error {
+	attrs := parseScramAttrs(serverFinal)
+	if e, ok := attrs["e"]; ok {
+		return fmt.Errorf("scram: %s", e)
+	}
+
+	serverKey := hmacSHA256(s.saltedPass, "Server Key")
+	expected := hmacSHA256(serverKey, s.authMessage)
+
+	got, err := base64.StdEncoding.DecodeString(attrs["v"])
+	if err != nil || !hmac.Equal(got, expected) {
+		return errors.New("scram: invalid server signature")
+	}
+
+	return nil
+}
+
+func parseScramAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		if len(kv) > 2 && kv[1] == '=' {
+			attrs[kv[:1]] = kv[2:]
+		}
+	}
+	return attrs
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data)) // nolint
+	return h.Sum(nil)
+}
@@ -0,0 +1,258 @@
+package waitforit_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+
+	. "github.com/maxcnunes/waitforit"
+)
+
+// fakePostgres implements just enough of the postgres wire protocol
+// to exercise the startup handshake performed by the postgres prober
+type fakePostgres struct {
+	auth       string // trust, cleartext, md5 or scram
+	password   string
+	startingUp bool
+}
+
+func (f *fakePostgres) start(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() }) // nolint
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func (f *fakePostgres) serve(c net.Conn) {
+	defer c.Close() // nolint
+	r := bufio.NewReader(c)
+
+	var n uint32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return
+	}
+	startup := make([]byte, n-4)
+	if _, err := io.ReadFull(r, startup); err != nil {
+		return
+	}
+	params := strings.Split(string(startup[4:]), "\x00")
+	user := params[1]
+
+	if f.startingUp {
+		pgSend(c, 'E', pgErrorBody("57P03", "the database system is starting up"))
+		return
+	}
+
+	if !f.authenticate(c, r, user) {
+		pgSend(c, 'E', pgErrorBody("28P01", "password authentication failed"))
+		return
+	}
+
+	pgSend(c, 'R', pgAuth(0, nil))
+	pgSend(c, 'S', []byte("server_version\x0016.0\x00"))
+	pgSend(c, 'Z', []byte("I"))
+
+	for {
+		t, body, err := pgReceive(r)
+		if err != nil || t == 'X' {
+			return
+		}
+
+		if t == 'Q' {
+			if strings.HasPrefix(string(body), "FAIL") {
+				pgSend(c, 'E', pgErrorBody("42601", "syntax error"))
+			} else {
+				pgSend(c, 'C', []byte("SELECT 1\x00"))
+			}
+			pgSend(c, 'Z', []byte("I"))
+		}
+	}
+}
+
+func (f *fakePostgres) authenticate(c net.Conn, r *bufio.Reader, user string) bool {
+	switch f.auth {
+	case "cleartext":
+		pgSend(c, 'R', pgAuth(3, nil))
+		_, body, err := pgReceive(r)
+		return err == nil && string(body) == f.password+"\x00"
+	case "md5":
+		salt := []byte{1, 2, 3, 4}
+		pgSend(c, 'R', pgAuth(5, salt))
+		_, body, err := pgReceive(r)
+		inner := md5.Sum([]byte(f.password + user))
+		outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+		return err == nil && string(body) == "md5"+hex.EncodeToString(outer[:])+"\x00"
+	case "scram":
+		return f.scram(c, r)
+	}
+
+	return true
+}
+
+func (f *fakePostgres) scram(c net.Conn, r *bufio.Reader) bool {
+	pgSend(c, 'R', pgAuth(10, []byte("SCRAM-SHA-256\x00\x00")))
+
+	_, body, err := pgReceive(r)
+	if err != nil {
+		return false
+	}
+	mechanism := bytes.IndexByte(body, 0)
+	clientFirstBare := strings.TrimPrefix(string(body[mechanism+5:]), "n,,")
+	clientNonce := strings.TrimPrefix(clientFirstBare, "n=,r=")
+
+	salt := []byte("saltsalt")
+	serverFirst := "r=" + clientNonce + "server,s=" + base64.StdEncoding.EncodeToString(salt) + ",i=4096"
+	pgSend(c, 'R', pgAuth(11, []byte(serverFirst)))
+
+	_, body, err = pgReceive(r)
+	if err != nil {
+		return false
+	}
+	clientFinal := string(body)
+	i := strings.LastIndex(clientFinal, ",p=")
+	proof, _ := base64.StdEncoding.DecodeString(clientFinal[i+3:])
+
+	salted, _ := pbkdf2.Key(sha256.New, f.password, salt, 4096, sha256.Size)
+	authMessage := clientFirstBare + "," + serverFirst + "," + clientFinal[:i]
+	clientKey := testHMAC(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	signature := testHMAC(storedKey[:], authMessage)
+	for i := range proof {
+		proof[i] ^= signature[i]
+	}
+	if got := sha256.Sum256(proof); !hmac.Equal(got[:], storedKey[:]) {
+		return false
+	}
+
+	serverSignature := testHMAC(testHMAC(salted, "Server Key"), authMessage)
+	pgSend(c, 'R', pgAuth(12, []byte("v="+base64.StdEncoding.EncodeToString(serverSignature))))
+	return true
+}
+
+func pgSend(w io.Writer, t byte, body []byte) {
+	msg := []byte{t}
+	msg = binary.BigEndian.AppendUint32(msg, uint32(len(body)+4))
+	w.Write(append(msg, body...)) // nolint
+}
+
+func pgReceive(r *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	body := make([]byte, binary.BigEndian.Uint32(header[1:])-4)
+	_, err := io.ReadFull(r, body)
+	return header[0], body, err
+}
+
+func pgAuth(code uint32, data []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, code), data...)
+}
+
+func pgErrorBody(code, message string) []byte {
+	return []byte("SFATAL\x00C" + code + "\x00M" + message + "\x00\x00")
+}
+
+func testHMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data)) // nolint
+	return h.Sum(nil)
+}
+
+func TestPingPostgres(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	testCases := []struct {
+		title    string
+		server   *fakePostgres
+		userinfo string
+		query    string
+		finishOk bool
+	}{
+		{
+			title:    "Should succeed with trust authentication",
+			server:   &fakePostgres{auth: "trust"},
+			finishOk: true,
+		},
+		{
+			title:    "Should succeed with cleartext authentication",
+			server:   &fakePostgres{auth: "cleartext", password: "secret"},
+			userinfo: "postgres:secret@",
+			finishOk: true,
+		},
+		{
+			title:    "Should succeed with md5 authentication",
+			server:   &fakePostgres{auth: "md5", password: "secret"},
+			userinfo: "app:secret@",
+			finishOk: true,
+		},
+		{
+			title:    "Should succeed with SCRAM-SHA-256 authentication",
+			server:   &fakePostgres{auth: "scram", password: "secret"},
+			userinfo: "app:secret@",
+			query:    "SELECT 1",
+			finishOk: true,
+		},
+		{
+			title:    "Should fail with wrong SCRAM-SHA-256 password",
+			server:   &fakePostgres{auth: "scram", password: "secret"},
+			userinfo: "app:wrong@",
+			finishOk: false,
+		},
+		{
+			title:    "Should fail while the database system is starting up",
+			server:   &fakePostgres{auth: "trust", startingUp: true},
+			finishOk: false,
+		},
+		{
+			title:    "Should fail when the query fails",
+			server:   &fakePostgres{auth: "trust"},
+			query:    "FAIL",
+			finishOk: false,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			address := "postgres://" + v.userinfo + v.server.start(t) + "/app"
+			conn, err := BuildConn(&Config{Address: address, Query: v.query, Timeout: 1, Retry: 200})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = DialConn(context.Background(), conn, logger)
+			if err != nil && v.finishOk {
+				t.Errorf("Expected to connect successfully %s. But got error %v.", address, err)
+			}
+
+			if err == nil && !v.finishOk {
+				t.Errorf("Expected to not connect successfully %s.", address)
+			}
+		})
+	}
+}
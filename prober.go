@@ -0,0 +1,56 @@
+package waitforit
+
+import (
+	"context"
+	"sync"
+)
+
+// Prober checks once if the target described by the connection is available.
+// A nil error means the target is up, any other value is handled as a failed
+// attempt and the target is probed again until it succeeds or times out.
+type Prober interface {
+	Probe(ctx context.Context, conn *Connection) error
+}
+
+// ProberFunc is an adapter to allow the use of ordinary functions as probers.
+type ProberFunc func(ctx context.Context, conn *Connection) error
+
+// Probe calls f(ctx, conn).
+func (f ProberFunc) Probe(ctx context.Context, conn *Connection) error {
+	return f(ctx, conn)
+}
+
+var (
+	probersMu sync.RWMutex
+	probers   = map[string]Prober{}
+)
+
+func init() {
+	RegisterProber("tcp", ProberFunc(pingHost))
+	RegisterProber("http", ProberFunc(pingAddress))
+	RegisterProber("https", ProberFunc(pingAddress))
+	RegisterProber("unix", ProberFunc(pingHost))
+	RegisterProber("http+unix", ProberFunc(pingAddress))
+}
+
+// RegisterProber makes a prober available for connections using the given
+// URL scheme. Registering the same scheme twice replaces the previous prober.
+func RegisterProber(scheme string, p Prober) {
+	probersMu.Lock()
+	defer probersMu.Unlock()
+
+	probers[scheme] = p
+}
+
+// LookupProber returns the prober registered for the scheme.
+// Schemes without a specific prober (e.g. ssh) fallback to the tcp one.
+func LookupProber(scheme string) Prober {
+	probersMu.RLock()
+	defer probersMu.RUnlock()
+
+	if p, ok := probers[scheme]; ok {
+		return p
+	}
+
+	return probers["tcp"]
+}
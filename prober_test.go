@@ -0,0 +1,110 @@
+package waitforit_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	. "github.com/maxcnunes/waitforit"
+)
+
+func TestRegisterProber(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	attempts := 0
+	RegisterProber("custom", ProberFunc(func(ctx context.Context, conn *Connection) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("not ready")
+		}
+		return nil
+	}))
+
+	conn, err := BuildConn(&Config{Address: "custom://localhost:1234", Timeout: 5, Retry: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DialConn(context.Background(), conn, logger); err != nil {
+		t.Fatalf("Expected custom prober to succeed, got %v", err)
+	}
+
+	assertEqual(t, "attempts", attempts, 3)
+}
+
+func TestSuccessThreshold(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	// flapping answers once and fails on the next attempt
+	attempts := 0
+	RegisterProber("flapping", ProberFunc(func(ctx context.Context, conn *Connection) error {
+		attempts++
+		if attempts%2 == 0 {
+			return errors.New("not ready")
+		}
+		return nil
+	}))
+	RegisterProber("healthy", ProberFunc(func(ctx context.Context, conn *Connection) error {
+		return nil
+	}))
+
+	testCases := []struct {
+		title       string
+		cfg         Config
+		finishOk    bool
+		minDuration time.Duration
+	}{
+		{
+			title:    "Should succeed on the first success by default",
+			cfg:      Config{Address: "flapping://localhost:1234"},
+			finishOk: true,
+		},
+		{
+			title:    "Should fail when the target never reaches the consecutive successes",
+			cfg:      Config{Address: "flapping://localhost:1234", SuccessThreshold: 2},
+			finishOk: false,
+		},
+		{
+			title:       "Should succeed once the target reaches the consecutive successes",
+			cfg:         Config{Address: "healthy://localhost:1234", SuccessThreshold: 3},
+			finishOk:    true,
+			minDuration: 100 * time.Millisecond,
+		},
+		{
+			title:       "Should succeed once the target is stable for the period",
+			cfg:         Config{Address: "healthy://localhost:1234", StableFor: 300},
+			finishOk:    true,
+			minDuration: 300 * time.Millisecond,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			attempts = 0
+			cfg := v.cfg
+			cfg.Timeout = 1
+			cfg.Retry = 50
+
+			conn, err := BuildConn(&cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			err = DialConn(context.Background(), conn, logger)
+			if err != nil && v.finishOk {
+				t.Errorf("Expected to connect successfully %s. But got error %v.", cfg.Address, err)
+			}
+
+			if err == nil && !v.finishOk {
+				t.Errorf("Expected to not connect successfully %s.", cfg.Address)
+			}
+
+			if elapsed := time.Since(start); elapsed < v.minDuration {
+				t.Errorf("Expected to keep probing for at least %v, took %v", v.minDuration, elapsed)
+			}
+		})
+	}
+}
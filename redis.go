@@ -0,0 +1,113 @@
+package waitforit
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterProber("redis", ProberFunc(pingRedis))
+	RegisterProber("rediss", ProberFunc(pingRedis))
+}
+
+// pingRedis check if the redis server answers PING with PONG.
+// It authenticates and selects the database given by the url first,
+// so errors like -LOADING while restoring a snapshot are handled as not ready.
+func pingRedis(ctx context.Context, conn *Connection) error {
+	c, err := dialTarget(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer c.Close() // nolint
+
+	if conn.URL.Scheme == "rediss" {
+		cfg, err := tlsConfig(conn.Config)
+		if err != nil {
+			return err
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName = conn.URL.Hostname()
+		}
+
+		tc := tls.Client(c, cfg)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			return err
+		}
+		c = tc
+	}
+
+	rd := &redisConn{r: bufio.NewReader(c), w: c}
+
+	if password, ok := conn.URL.User.Password(); ok {
+		args := []string{"AUTH", password}
+		if user := conn.URL.User.Username(); user != "" {
+			args = []string{"AUTH", user, password}
+		}
+		if _, err := rd.do(args...); err != nil {
+			return err
+		}
+	}
+
+	if db := strings.TrimPrefix(conn.URL.Path, "/"); db != "" {
+		if _, err := strconv.Atoi(db); err != nil {
+			return fmt.Errorf("redis: invalid database %q", db)
+		}
+		if _, err := rd.do("SELECT", db); err != nil {
+			return err
+		}
+	}
+
+	reply, err := rd.do("PING")
+	if err != nil {
+		return err
+	}
+
+	if reply != "PONG" {
+		return fmt.Errorf("redis: unexpected PING reply %q", reply)
+	}
+
+	return nil
+}
+
+type redisConn struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+// do sends a command using RESP and returns its simple string reply
+func (rd *redisConn) do(args ...string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+
+	if _, err := io.WriteString(rd.w, b.String()); err != nil {
+		return "", err
+	}
+
+	line, err := rd.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+
+	if line == "" {
+		return "", errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("redis: %s", line[1:])
+	}
+
+	return "", fmt.Errorf("redis: unexpected reply %q", line)
+}
@@ -0,0 +1,164 @@
+package waitforit_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/maxcnunes/waitforit"
+)
+
+// fakeRedis implements the few RESP commands sent by the redis prober
+type fakeRedis struct {
+	password string
+	loading  bool
+	tls      bool
+}
+
+func (f *fakeRedis) start(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.tls {
+		s := httptest.NewUnstartedServer(nil)
+		s.StartTLS()
+		s.Close()
+		l = tls.NewListener(l, s.TLS)
+	}
+	t.Cleanup(func() { l.Close() }) // nolint
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func (f *fakeRedis) serve(c net.Conn) {
+	defer c.Close() // nolint
+	r := bufio.NewReader(c)
+	authenticated := f.password == ""
+
+	for {
+		args, err := redisReadCommand(r)
+		if err != nil {
+			return
+		}
+
+		switch {
+		case args[0] == "AUTH" && args[len(args)-1] == f.password:
+			authenticated = true
+			io.WriteString(c, "+OK\r\n") // nolint
+		case args[0] == "AUTH":
+			io.WriteString(c, "-WRONGPASS invalid username-password pair\r\n") // nolint
+		case !authenticated:
+			io.WriteString(c, "-NOAUTH Authentication required.\r\n") // nolint
+		case f.loading:
+			io.WriteString(c, "-LOADING Redis is loading the dataset in memory\r\n") // nolint
+		case args[0] == "SELECT":
+			io.WriteString(c, "+OK\r\n") // nolint
+		case args[0] == "PING":
+			io.WriteString(c, "+PONG\r\n") // nolint
+		}
+	}
+}
+
+func redisReadCommand(r *bufio.Reader) ([]string, error) {
+	var n int
+	if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+
+	return args, nil
+}
+
+func TestPingRedis(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	testCases := []struct {
+		title    string
+		server   *fakeRedis
+		address  string
+		finishOk bool
+	}{
+		{
+			title:    "Should succeed when the server answers PONG",
+			server:   &fakeRedis{},
+			address:  "redis://%s",
+			finishOk: true,
+		},
+		{
+			title:    "Should succeed authenticating and selecting a database",
+			server:   &fakeRedis{password: "secret"},
+			address:  "redis://:secret@%s/2",
+			finishOk: true,
+		},
+		{
+			title:    "Should fail with a wrong password",
+			server:   &fakeRedis{password: "secret"},
+			address:  "redis://:wrong@%s",
+			finishOk: false,
+		},
+		{
+			title:    "Should fail while the server is loading the dataset",
+			server:   &fakeRedis{loading: true},
+			address:  "redis://%s",
+			finishOk: false,
+		},
+		{
+			title:    "Should succeed over TLS",
+			server:   &fakeRedis{tls: true},
+			address:  "rediss://%s",
+			finishOk: true,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			address := fmt.Sprintf(v.address, v.server.start(t))
+			conn, err := BuildConn(&Config{Address: address, Insecure: true, Timeout: 1, Retry: 200})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = DialConn(context.Background(), conn, logger)
+			if err != nil && v.finishOk {
+				t.Errorf("Expected to connect successfully %s. But got error %v.", address, err)
+			}
+
+			if err == nil && !v.finishOk {
+				t.Errorf("Expected to not connect successfully %s.", address)
+			}
+		})
+	}
+}
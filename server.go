@@ -0,0 +1,78 @@
+package waitforit
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// StatusHandler serves the status of the monitored targets:
+// /live always answers OK while the process is running,
+// /ready answers OK only when the monitor is ready (503 otherwise)
+// and /status describes every target as JSON.
+func StatusHandler(m *Monitor) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK")) // nolint
+	})
+
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		if !m.Ready() {
+			http.Error(w, "Not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("OK")) // nolint
+	})
+
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		status := struct {
+			Ready   bool               `json:"ready"`
+			Targets []targetStatusJSON `json:"targets"`
+		}{Ready: m.Ready()}
+
+		for _, s := range m.Status() {
+			status.Targets = append(status.Targets, newTargetStatusJSON(s))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status) // nolint
+	})
+
+	return mux
+}
+
+// targetStatusJSON is the representation of a TargetStatus served by /status
+type targetStatusJSON struct {
+	Name      string     `json:"name,omitempty"`
+	Group     string     `json:"group,omitempty"`
+	Address   string     `json:"address"`
+	Up        bool       `json:"up"`
+	Successes int        `json:"successes"`
+	Failures  int        `json:"failures"`
+	LastError string     `json:"lastError,omitempty"`
+	LatencyMs float64    `json:"latencyMs"`
+	CheckedAt *time.Time `json:"checkedAt,omitempty"`
+}
+
+func newTargetStatusJSON(s TargetStatus) targetStatusJSON {
+	j := targetStatusJSON{
+		Name:      s.Name,
+		Group:     s.Group,
+		Address:   s.Address,
+		Up:        s.Up,
+		Successes: s.Successes,
+		Failures:  s.Failures,
+		LatencyMs: float64(s.Latency.Microseconds()) / 1000,
+	}
+
+	if s.Err != nil {
+		j.LastError = s.Err.Error()
+	}
+
+	if !s.CheckedAt.IsZero() {
+		j.CheckedAt = &s.CheckedAt
+	}
+
+	return j
+}
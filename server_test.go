@@ -0,0 +1,98 @@
+package waitforit_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/maxcnunes/waitforit"
+)
+
+func TestStatusHandler(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close() // nolint
+
+	m, err := NewMonitor(FileConfig{
+		Configs: []Config{
+			{Name: "db", Address: "tcp://" + l.Addr().String(), Retry: 50},
+			{Name: "cache", Address: "localhost:8087", Retry: 50},
+		},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := httptest.NewServer(StatusHandler(m))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	waitFor(t, "probed", func() bool {
+		for _, s := range m.Status() {
+			if s.CheckedAt.IsZero() {
+				return false
+			}
+		}
+		return true
+	})
+
+	testCases := []struct {
+		path   string
+		status int
+	}{
+		{path: "/live", status: http.StatusOK},
+		{path: "/ready", status: http.StatusServiceUnavailable},
+		{path: "/status", status: http.StatusOK},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.path, func(t *testing.T) {
+			resp, err := http.Get(s.URL + v.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close() // nolint
+
+			assertEqual(t, "status code", resp.StatusCode, v.status)
+		})
+	}
+
+	resp, err := http.Get(s.URL + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close() // nolint
+
+	var status struct {
+		Ready   bool
+		Targets []struct {
+			Name      string
+			Up        bool
+			LastError string
+			LatencyMs float64
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqual(t, "ready", status.Ready, false)
+	assertEqual(t, "targets", len(status.Targets), 2)
+	assertEqual(t, "name", status.Targets[0].Name, "db")
+	assertEqual(t, "db up", status.Targets[0].Up, true)
+	assertEqual(t, "cache up", status.Targets[1].Up, false)
+	if status.Targets[1].LastError == "" {
+		t.Error("Expected cache to report its last error")
+	}
+}
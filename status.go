@@ -0,0 +1,89 @@
+package waitforit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// StatusCodes describes the http status codes accepted as available.
+// It is a comma separated list of codes and ranges (e.g. "200-299,301,401").
+// When empty (or "0", the former default of -status) any status below 500 is accepted.
+type StatusCodes string
+
+// isDefault reports if the expression accepts any status below 500
+func (s StatusCodes) isDefault() bool {
+	return s == "" || strings.TrimSpace(string(s)) == "0"
+}
+
+// UnmarshalJSON accepts both a string expression and
+// a plain integer for backward compatibility (e.g. "status": 200)
+func (s *StatusCodes) UnmarshalJSON(b []byte) error {
+	var code int
+	if err := json.Unmarshal(b, &code); err == nil {
+		if code > 0 {
+			*s = StatusCodes(strconv.Itoa(code))
+		} else {
+			*s = ""
+		}
+		return nil
+	}
+
+	var expr string
+	if err := json.Unmarshal(b, &expr); err != nil {
+		return fmt.Errorf("Invalid status %s: must be a number or a string", b)
+	}
+
+	*s = StatusCodes(expr)
+	return nil
+}
+
+// Match checks if the status code is accepted
+func (s StatusCodes) Match(code int) (bool, error) {
+	if s.isDefault() {
+		return code < http.StatusInternalServerError, nil
+	}
+
+	ranges, err := s.parse()
+	if err != nil {
+		return false, err
+	}
+
+	for _, r := range ranges {
+		if code >= r[0] && code <= r[1] {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s StatusCodes) parse() ([][2]int, error) {
+	var ranges [][2]int
+	for _, part := range strings.Split(string(s), ",") {
+		part = strings.TrimSpace(part)
+		bounds := strings.SplitN(part, "-", 2)
+
+		min, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid status %q", part)
+		}
+
+		max := min
+		if len(bounds) == 2 {
+			if max, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil {
+				return nil, fmt.Errorf("Invalid status %q", part)
+			}
+		}
+
+		if min < 100 || max > 599 || min > max {
+			return nil, fmt.Errorf("Invalid status %q", part)
+		}
+
+		ranges = append(ranges, [2]int{min, max})
+	}
+
+	return ranges, nil
+}
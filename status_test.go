@@ -0,0 +1,79 @@
+package waitforit_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/maxcnunes/waitforit"
+)
+
+func TestStatusCodesMatch(t *testing.T) {
+	testCases := []struct {
+		status StatusCodes
+		code   int
+		match  bool
+	}{
+		{"", 200, true},
+		{"", 404, true},
+		{"", 500, false},
+		{"0", 404, true},
+		{"0", 500, false},
+		{"200", 200, true},
+		{"200", 201, false},
+		{"200-299,301,401", 204, true},
+		{"200-299,301,401", 301, true},
+		{"200-299,301,401", 401, true},
+		{"200-299,301,401", 404, false},
+		{" 200 - 204 , 302 ", 302, true},
+	}
+
+	for _, v := range testCases {
+		match, err := v.status.Match(v.code)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if match != v.match {
+			t.Errorf("Expected %q matching %d to be %v", v.status, v.code, v.match)
+		}
+	}
+}
+
+func TestStatusCodesUnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		json     string
+		expected StatusCodes
+		err      bool
+	}{
+		{json: `{"status": 200}`, expected: "200"},
+		{json: `{"status": 0}`, expected: ""},
+		{json: `{"status": "200-299,301"}`, expected: "200-299,301"},
+		{json: `{}`, expected: ""},
+		{json: `{"status": true}`, err: true},
+	}
+
+	for _, v := range testCases {
+		var cfg Config
+		err := json.Unmarshal([]byte(v.json), &cfg)
+		if v.err {
+			if err == nil {
+				t.Errorf("Expected %s to fail", v.json)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertEqual(t, "status", cfg.Status, v.expected)
+	}
+}
+
+func TestStatusCodesValidation(t *testing.T) {
+	for _, status := range []StatusCodes{"abc", "200-", "299-200", "99", "200,,301"} {
+		if _, err := BuildConn(&Config{Address: "http://localhost", Status: status}); err == nil {
+			t.Errorf("Expected status %q to be invalid", status)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package waitforit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+func init() {
+	RegisterProber("tls", ProberFunc(pingTLS))
+	RegisterProber("tls+postgres", ProberFunc(pingTLS))
+}
+
+// tlsConfig builds the tls config used to reach the target
+// with the custom CA bundle, client certificate and SNI from the config
+func tlsConfig(conf *Config) (*tls.Config, error) {
+	tc := &tls.Config{
+		InsecureSkipVerify: conf.Insecure, // nolint gosec
+		ServerName:         conf.ServerName,
+	}
+
+	if conf.CAFile != "" {
+		pem, err := os.ReadFile(conf.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading CA file: %v", err)
+		}
+
+		tc.RootCAs = x509.NewCertPool()
+		if !tc.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in CA file %s", conf.CAFile)
+		}
+	}
+
+	if conf.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading client certificate: %v", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+
+	return tc, nil
+}
+
+// pingTLS check if the target completes a tls handshake (without speaking
+// any application protocol) and its certificate satisfies the config
+// assertions: valid for a hostname, not expiring soon and the expected issuer.
+// On tls+postgres the handshake starts after the postgres SSLRequest message.
+func pingTLS(ctx context.Context, conn *Connection) error {
+	conf := conn.Config
+
+	c, err := dialTarget(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer c.Close() // nolint
+
+	if conn.URL.Scheme == "tls+postgres" {
+		if err := pgSSLRequest(c); err != nil {
+			return err
+		}
+	}
+
+	tc, err := tlsConfig(conf)
+	if err != nil {
+		return err
+	}
+	if tc.ServerName == "" {
+		tc.ServerName = conn.URL.Hostname()
+	}
+
+	client := tls.Client(c, tc)
+	if err := client.HandshakeContext(ctx); err != nil {
+		return err
+	}
+
+	certs := client.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return errors.New("tls: no certificate presented by the server")
+	}
+
+	return checkCertificate(conf, certs[0])
+}
+
+// checkCertificate asserts the server certificate matches the config expectations
+func checkCertificate(conf *Config, cert *x509.Certificate) error {
+	if conf.CertHostname != "" {
+		if err := cert.VerifyHostname(conf.CertHostname); err != nil {
+			return fmt.Errorf("tls: %v", err)
+		}
+	}
+
+	if conf.CertMinDays > 0 {
+		minExpiry := time.Now().Add(time.Duration(conf.CertMinDays) * 24 * time.Hour)
+		if cert.NotAfter.Before(minExpiry) {
+			return fmt.Errorf("tls: certificate expires at %s, within %d days", cert.NotAfter.Format(time.RFC3339), conf.CertMinDays)
+		}
+	}
+
+	if conf.CertIssuer != "" && !matchIssuer(cert, conf.CertIssuer) {
+		return fmt.Errorf("tls: certificate issued by %q, expected %q", cert.Issuer.String(), conf.CertIssuer)
+	}
+
+	return nil
+}
+
+// matchIssuer checks the issuer common name, organization or full distinguished name
+func matchIssuer(cert *x509.Certificate, issuer string) bool {
+	if cert.Issuer.CommonName == issuer || cert.Issuer.String() == issuer {
+		return true
+	}
+
+	for _, o := range cert.Issuer.Organization {
+		if o == issuer {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,382 @@
+package waitforit_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/maxcnunes/waitforit"
+)
+
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+// newTestCert creates a certificate signed by the parent (self signed when nil)
+// and writes it with its key as PEM files in the test temp dir
+func newTestCert(t *testing.T, name string, parent *testCert, tmpl *x509.Certificate) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.Subject = pkix.Name{CommonName: name}
+	if tmpl.NotBefore.IsZero() {
+		tmpl.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if tmpl.NotAfter.IsZero() {
+		tmpl.NotAfter = time.Now().Add(365 * 24 * time.Hour)
+	}
+
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	c := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".pem"),
+		keyFile:  filepath.Join(dir, name+"-key.pem"),
+	}
+
+	if err := os.WriteFile(c.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(c.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func newTestCA(t *testing.T, name string) *testCert {
+	return newTestCert(t, name, nil, &x509.Certificate{
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+}
+
+func newTestServerCert(t *testing.T, ca *testCert, dnsName string, notAfter time.Time) *testCert {
+	return newTestCert(t, dnsName, ca, &x509.Certificate{
+		DNSNames:    []string{dnsName},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		NotAfter:    notAfter,
+	})
+}
+
+func TestMutualTLS(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	ca := newTestCA(t, "ca")
+	serverCert := newTestServerCert(t, ca, "api.internal", time.Time{})
+	clientCert := newTestCert(t, "client", ca, &x509.Certificate{
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	otherCA := newTestCA(t, "other-ca")
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK")) // nolint
+	}))
+	s.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert.tlsCertificate()},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	s.StartTLS()
+	defer s.Close()
+
+	testCases := []struct {
+		title    string
+		cfg      Config
+		finishOk bool
+	}{
+		{
+			title:    "Should succeed with the CA bundle and client certificate",
+			cfg:      Config{CAFile: ca.certFile, CertFile: clientCert.certFile, KeyFile: clientCert.keyFile},
+			finishOk: true,
+		},
+		{
+			title:    "Should succeed overriding the server name",
+			cfg:      Config{CAFile: ca.certFile, CertFile: clientCert.certFile, KeyFile: clientCert.keyFile, ServerName: "api.internal"},
+			finishOk: true,
+		},
+		{
+			title:    "Should fail when the server name does not match the certificate",
+			cfg:      Config{CAFile: ca.certFile, CertFile: clientCert.certFile, KeyFile: clientCert.keyFile, ServerName: "other.internal"},
+			finishOk: false,
+		},
+		{
+			title:    "Should fail without the client certificate",
+			cfg:      Config{CAFile: ca.certFile},
+			finishOk: false,
+		},
+		{
+			title:    "Should fail when the server is signed by an unknown CA",
+			cfg:      Config{CAFile: otherCA.certFile, CertFile: clientCert.certFile, KeyFile: clientCert.keyFile},
+			finishOk: false,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			cfg := v.cfg
+			cfg.Address = s.URL
+			cfg.Timeout = 1
+			cfg.Retry = 200
+
+			conn, err := BuildConn(&cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = DialConn(context.Background(), conn, logger)
+			if err != nil && v.finishOk {
+				t.Errorf("Expected to connect successfully %s. But got error %v.", cfg.Address, err)
+			}
+
+			if err == nil && !v.finishOk {
+				t.Errorf("Expected to not connect successfully %s.", cfg.Address)
+			}
+		})
+	}
+}
+
+func TestMutualTLSValidation(t *testing.T) {
+	if _, err := BuildConn(&Config{Address: "https://localhost", CertFile: "client.pem"}); err == nil {
+		t.Error("Expected certFile without keyFile to be invalid")
+	}
+}
+
+func TestPingTLS(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	ca := newTestCA(t, "Example CA")
+	valid := newTestServerCert(t, ca, "ldap.example.com", time.Time{})
+	expiring := newTestServerCert(t, ca, "ldap.example.com", time.Now().Add(3*24*time.Hour))
+
+	startTLS := func(cert *testCert) string {
+		l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert.tlsCertificate()}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { l.Close() }) // nolint
+
+		go func() {
+			for {
+				c, err := l.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					c.(*tls.Conn).Handshake() // nolint
+					c.Close()                 // nolint
+				}()
+			}
+		}()
+
+		return l.Addr().String()
+	}
+
+	validAddr := startTLS(valid)
+	expiringAddr := startTLS(expiring)
+
+	testCases := []struct {
+		title    string
+		address  string
+		cfg      Config
+		finishOk bool
+	}{
+		{
+			title:    "Should succeed completing the handshake",
+			address:  validAddr,
+			cfg:      Config{CAFile: ca.certFile},
+			finishOk: true,
+		},
+		{
+			title:    "Should fail when the certificate is not trusted",
+			address:  validAddr,
+			finishOk: false,
+		},
+		{
+			title:    "Should succeed asserting hostname, expiry and issuer",
+			address:  validAddr,
+			cfg:      Config{CAFile: ca.certFile, CertHostname: "ldap.example.com", CertMinDays: 30, CertIssuer: "Example CA"},
+			finishOk: true,
+		},
+		{
+			title:    "Should assert the certificate even when insecure",
+			address:  validAddr,
+			cfg:      Config{Insecure: true, CertHostname: "other.example.com"},
+			finishOk: false,
+		},
+		{
+			title:    "Should fail when the certificate expires within the minimum days",
+			address:  expiringAddr,
+			cfg:      Config{CAFile: ca.certFile, CertMinDays: 7},
+			finishOk: false,
+		},
+		{
+			title:    "Should fail when the certificate has a different issuer",
+			address:  validAddr,
+			cfg:      Config{CAFile: ca.certFile, CertIssuer: "Other CA"},
+			finishOk: false,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			cfg := v.cfg
+			cfg.Address = "tls://" + v.address
+			cfg.Timeout = 1
+			cfg.Retry = 200
+
+			conn, err := BuildConn(&cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = DialConn(context.Background(), conn, logger)
+			if err != nil && v.finishOk {
+				t.Errorf("Expected to connect successfully %s. But got error %v.", cfg.Address, err)
+			}
+
+			if err == nil && !v.finishOk {
+				t.Errorf("Expected to not connect successfully %s.", cfg.Address)
+			}
+		})
+	}
+}
+
+func TestPingTLSPostgres(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	ca := newTestCA(t, "Example CA")
+	cert := newTestServerCert(t, ca, "localhost", time.Time{})
+
+	// startPostgres answers the SSLRequest and, when accepted, completes the handshake
+	startPostgres := func(answer byte) string {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { l.Close() }) // nolint
+
+		go func() {
+			for {
+				c, err := l.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					defer c.Close() // nolint
+
+					req := make([]byte, 8)
+					if _, err := io.ReadFull(c, req); err != nil || binary.BigEndian.Uint32(req[4:]) != 80877103 {
+						return
+					}
+					c.Write([]byte{answer}) // nolint
+					if answer == 'S' {
+						tls.Server(c, &tls.Config{Certificates: []tls.Certificate{cert.tlsCertificate()}}).Handshake() // nolint
+					}
+				}()
+			}
+		}()
+
+		return l.Addr().String()
+	}
+
+	testCases := []struct {
+		title    string
+		address  string
+		finishOk bool
+	}{
+		{
+			title:    "Should succeed once the server accepts the tls request",
+			address:  "tls+postgres://" + startPostgres('S'),
+			finishOk: true,
+		},
+		{
+			title:    "Should fail when the server refuses the tls request",
+			address:  "tls+postgres://" + startPostgres('N'),
+			finishOk: false,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			conn, err := BuildConn(&Config{Address: v.address, CAFile: ca.certFile, ServerName: "localhost", Timeout: 1, Retry: 200})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = DialConn(context.Background(), conn, logger)
+			if err != nil && v.finishOk {
+				t.Errorf("Expected to connect successfully %s. But got error %v.", v.address, err)
+			}
+
+			if err == nil && !v.finishOk {
+				t.Errorf("Expected to not connect successfully %s.", v.address)
+			}
+		})
+	}
+}
+
+func TestBuildConnTLSWithoutPort(t *testing.T) {
+	if _, err := BuildConn(&Config{Address: "tls://ldap"}); err == nil {
+		t.Error("Expected a tls address without port to be invalid")
+	}
+
+	conn, err := BuildConn(&Config{Address: "tls+postgres://db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, "host", conn.URL.Host, "db:5432")
+}
@@ -0,0 +1,57 @@
+package waitforit
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+)
+
+func init() {
+	RegisterProber("udp", ProberFunc(pingUDP))
+}
+
+// pingUDP check if the host (hostname:port) answers an udp datagram.
+// Since udp is connectionless the target is only considered up
+// once any response (or one matching the response pattern) is received.
+func pingUDP(ctx context.Context, conn *Connection) error {
+	conf := conn.Config
+
+	c, err := dialTarget(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer c.Close() // nolint
+
+	payload := []byte(conf.Payload)
+	if conf.PayloadHex != "" {
+		if payload, err = hex.DecodeString(conf.PayloadHex); err != nil {
+			return err
+		}
+	}
+
+	if _, err := c.Write(payload); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 64*1024)
+	n, err := c.Read(buf)
+	if err != nil {
+		return err
+	}
+
+	if conf.ResponsePattern == "" {
+		return nil
+	}
+
+	re, err := regexp.Compile(conf.ResponsePattern)
+	if err != nil {
+		return err
+	}
+
+	if !re.Match(buf[:n]) {
+		return fmt.Errorf("Response %q does not match %q", buf[:n], conf.ResponsePattern)
+	}
+
+	return nil
+}
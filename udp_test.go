@@ -0,0 +1,109 @@
+package waitforit_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+
+	. "github.com/maxcnunes/waitforit"
+)
+
+func startUDPEcho(t *testing.T, reply func(req []byte) []byte) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() }) // nolint
+
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			if resp := reply(buf[:n]); resp != nil {
+				pc.WriteTo(resp, addr) // nolint
+			}
+		}
+	}()
+
+	return pc.LocalAddr().String()
+}
+
+func TestPingUDP(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	echo := func(req []byte) []byte { return req }
+	silent := func(req []byte) []byte { return nil }
+	pong := func(req []byte) []byte {
+		if bytes.Equal(req, []byte{0xca, 0xfe}) {
+			return []byte("pong")
+		}
+		return []byte("nope")
+	}
+
+	testCases := []struct {
+		title    string
+		cfg      Config
+		reply    func(req []byte) []byte
+		finishOk bool
+	}{
+		{
+			title:    "Should succeed when the target answers any response",
+			cfg:      Config{Payload: "ping"},
+			reply:    echo,
+			finishOk: true,
+		},
+		{
+			title:    "Should succeed when the response matches the pattern",
+			cfg:      Config{PayloadHex: "cafe", ResponsePattern: "^pong$"},
+			reply:    pong,
+			finishOk: true,
+		},
+		{
+			title:    "Should fail when the response does not match the pattern",
+			cfg:      Config{Payload: "ping", ResponsePattern: "^pong$"},
+			reply:    pong,
+			finishOk: false,
+		},
+		{
+			title:    "Should fail when the target never answers",
+			cfg:      Config{Payload: "ping"},
+			reply:    silent,
+			finishOk: false,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.title, func(t *testing.T) {
+			cfg := v.cfg
+			cfg.Address = "udp://" + startUDPEcho(t, v.reply)
+			cfg.Timeout = 1
+			cfg.Retry = 100
+
+			conn, err := BuildConn(&cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = DialConn(context.Background(), conn, logger)
+			if err != nil && v.finishOk {
+				t.Errorf("Expected to connect successfully %s. But got error %v.", cfg.Address, err)
+			}
+
+			if err == nil && !v.finishOk {
+				t.Errorf("Expected to not connect successfully %s.", cfg.Address)
+			}
+		})
+	}
+}
+
+func TestBuildConnInvalidPayload(t *testing.T) {
+	if _, err := BuildConn(&Config{Address: "udp://localhost:53", PayloadHex: "zz"}); err == nil {
+		t.Error("Expected invalid hex payload to fail")
+	}
+}